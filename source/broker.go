@@ -3,30 +3,34 @@ package router
 import (
 	"log/slog"
 
+	cmap "github.com/orcaman/concurrent-map/v2"
 	wamp "github.com/wamp3hub/wamp3go"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
 
 	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
-type SubscriptionList = routerShared.ResourceList[*wamp.SubscribeOptions]
+type SubscriptionList = routerShared.ResourceList[*routerShared.SubscribeOptions]
 
 type Broker struct {
 	routerID      string
-	peers         map[string]*wamp.Peer
-	subscriptions *routerShared.URIM[*wamp.SubscribeOptions]
+	peers         cmap.ConcurrentMap[string, *wamp.Peer]
+	subscriptions *routerShared.URIM[*routerShared.SubscribeOptions]
+	authorizer    *routerShared.Authorizer
 	logger        *slog.Logger
 }
 
 func NewBroker(
 	routerID string,
 	storage routerShared.Storage,
+	authorizer *routerShared.Authorizer,
 	logger *slog.Logger,
 ) *Broker {
 	return &Broker{
 		routerID,
-		make(map[string]*wamp.Peer),
-		routerShared.NewURIM[*wamp.SubscribeOptions](storage, logger),
+		cmap.New[*wamp.Peer](),
+		routerShared.NewURIM[*routerShared.SubscribeOptions]("subscriptions", storage, logger),
+		authorizer,
 		logger.With("name", "Broker"),
 	}
 }
@@ -56,6 +60,13 @@ func (broker *Broker) onPublish(publisher *wamp.Peer, request wamp.PublishEvent)
 	)
 	broker.logger.Debug("publish", requestLogData)
 
+	e = broker.authorizer.Authorize(publisher.ID, routerShared.ACTION_PUBLISH, features.URI)
+	if e != nil {
+		// publishers do not await reply, so unauthorized publications are dropped
+		broker.logger.Debug("publication dropped (not authorized)", requestLogData)
+		return e
+	}
+
 	includeSet := routerShared.NewSet(features.Include)
 	excludeSet := routerShared.NewSet(features.Exclude)
 
@@ -74,16 +85,22 @@ func (broker *Broker) onPublish(publisher *wamp.Peer, request wamp.PublishEvent)
 			continue
 		}
 
-		subscriber, exist := broker.peers[subscription.AuthorID]
+		subscriber, exist := broker.peers.Get(subscription.AuthorID)
 		if !exist {
 			broker.logger.Error("invalid subscription (peer not found)", subscriptionLogData, requestLogData)
 			continue
 		}
 
-		route.EndpointID = subscription.ID
-		route.SubscriberID = subscriber.ID
+		// every subscriber gets own route, because local peers share events
+		subscriberRoute := &wamp.PublishRoute{
+			PublisherID:    route.PublisherID,
+			SubscriberID:   subscriber.ID,
+			EndpointID:     subscription.ID,
+			VisitedRouters: route.VisitedRouters,
+		}
+		publication := wamp.MakePublishEvent(request.ID(), features, request.Payload(), subscriberRoute)
 
-		ok := subscriber.Send(request, wamp.DEFAULT_RESEND_COUNT)
+		ok := subscriber.Send(publication, wamp.DEFAULT_RESEND_COUNT)
 		if ok {
 			broker.logger.Debug("publication sent", subscriptionLogData, requestLogData)
 		} else {
@@ -95,13 +112,13 @@ func (broker *Broker) onPublish(publisher *wamp.Peer, request wamp.PublishEvent)
 }
 
 func (broker *Broker) onLeave(peer *wamp.Peer) {
-	delete(broker.peers, peer.ID)
+	broker.peers.Remove(peer.ID)
 	broker.logger.Debug("dettach peer", "ID", peer.ID)
 }
 
 func (broker *Broker) onJoin(peer *wamp.Peer) {
 	broker.logger.Debug("attach peer", "ID", peer.ID)
-	broker.peers[peer.ID] = peer
+	broker.peers.Set(peer.ID, peer)
 	peer.IncomingPublishEvents.Observe(
 		func(event wamp.PublishEvent) { broker.onPublish(peer, event) },
 		func() { broker.onLeave(peer) },
@@ -2,36 +2,49 @@ package generateTicket
 
 import (
 	"log"
+	"log/slog"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 	wamp "github.com/wamp3hub/wamp3go"
+	wampSerializers "github.com/wamp3hub/wamp3go/serializers"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
 	wampTransports "github.com/wamp3hub/wamp3go/transports"
+
+	router "github.com/wamp3hub/wamp3router/source"
 )
 
 func GenerateTicket(
 	unixPath string,
 	peerID string,
 	duration time.Duration,
+	role string,
 ) {
-	session, e := wampTransports.UnixJoin(&wampTransports.UnixJoinOptions{Path: "/tmp/wamp3rd.socket"})
+	// connection is closed by router after leave, peer warns about it until exit
+	session, e := wampTransports.UnixJoin(
+		&wampTransports.UnixJoinOptions{
+			Path:           unixPath,
+			Serializer:     wampSerializers.DefaultSerializer,
+			LoggingHandler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}),
+		},
+	)
+	if e != nil {
+		log.Printf("join error=%s", e)
+		return
+	}
+	defer wamp.Leave(session, "done")
+
+	pendingResponse := wamp.Call[string](
+		session,
+		&wamp.CallFeatures{URI: "wamp.ticket.generate", Timeout: 10},
+		router.GenerateTicketPayload{PeerID: peerID, Duration: duration, Role: role},
+	)
+	_, ticket, e := pendingResponse.Await()
 	if e == nil {
-		type GenerateTicketPayload struct {
-			PeerID   string
-			Duration time.Duration
-		}
-		pendingResponse := wamp.Call[string](
-			session,
-			&wamp.CallFeatures{URI: "wamp.ticket.generate"},
-			GenerateTicketPayload{peerID, time.Minute * duration},
-		)
-		_, ticket, e := pendingResponse.Await()
-		if e == nil {
-			log.Print(ticket)
-		} else {
-			log.Printf("generate ticket error=%s", e)
-		}
+		log.Print(ticket)
+	} else {
+		log.Printf("generate ticket error=%s", e)
 	}
 }
 
@@ -39,17 +52,19 @@ var (
 	unixPathFlag *string
 	peerIDFlag   *string
 	durationFlag *time.Duration
+	roleFlag     *string
 	Command      = &cobra.Command{
 		Use:   "generate-ticket",
 		Short: "Generates new authentication ticket",
 		Run: func(cmd *cobra.Command, args []string) {
-			GenerateTicket(*unixPathFlag, *peerIDFlag, *durationFlag)
+			GenerateTicket(*unixPathFlag, *peerIDFlag, *durationFlag, *roleFlag)
 		},
 	}
 )
 
 func init() {
-	unixPathFlag = Command.Flags().String("unix-path", "", "unix-path")
+	unixPathFlag = Command.Flags().String("unix-path", "/tmp/wamp3rd.socket", "unix socket path of running router")
 	peerIDFlag = Command.Flags().String("peer", wampShared.NewID(), "peer id")
-	durationFlag = Command.Flags().Duration("duration", 1440, "duration minutes")
+	durationFlag = Command.Flags().Duration("duration", 24*time.Hour, "ticket lifetime")
+	roleFlag = Command.Flags().String("role", "", "role of peer (empty means anonymous)")
 }
@@ -1,10 +1,12 @@
 package run
 
 import (
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
@@ -33,6 +35,58 @@ func ReadKeyPair(
 	return keyRing
 }
 
+// returns router id kept in storage, generates and keeps new one on first run,
+// so restored resources are recognized after restart
+func ReadRouterID(storage routerShared.Storage) (string, error) {
+	routerID := ""
+	e := storage.Get("router", "ID", &routerID)
+	if e == nil {
+		return routerID, nil
+	} else if !errors.Is(e, routerStorages.ErrorBucketNotFound) && !errors.Is(e, routerStorages.ErrorRecordNotFound) {
+		return "", e
+	}
+
+	routerID = wampShared.NewID()
+	e = storage.Set("router", "ID", routerID)
+	return routerID, e
+}
+
+// returns nil if rules path is not set, so everything is allowed
+func ReadAuthorizer(
+	rulesPath string,
+	logger *slog.Logger,
+) (*routerShared.Authorizer, error) {
+	if len(rulesPath) == 0 {
+		return nil, nil
+	}
+
+	rules, e := routerShared.ReadRules(rulesPath)
+	if e != nil {
+		return nil, e
+	}
+	return routerShared.NewAuthorizer(rules, logger)
+}
+
+// reloads rules on SIGHUP, keeps previous rules if new ones are invalid
+func reloadRulesOnHangup(
+	authorizer *routerShared.Authorizer,
+	rulesPath string,
+	logger *slog.Logger,
+) {
+	hangupSignal := make(chan os.Signal, 1)
+	signal.Notify(hangupSignal, syscall.SIGHUP)
+	for range hangupSignal {
+		logger.Info("reloading rules", "rulesPath", rulesPath)
+		rules, e := routerShared.ReadRules(rulesPath)
+		if e == nil {
+			e = authorizer.Load(rules)
+		}
+		if e != nil {
+			logger.Error("during reload rules", "error", e, "rulesPath", rulesPath)
+		}
+	}
+}
+
 func Run(
 	routerID string,
 	http2address string,
@@ -41,6 +95,13 @@ func Run(
 	storageClass string,
 	storagePath string,
 	privateKeyPath string,
+	gracePeriod time.Duration,
+	writeBehindInterval time.Duration,
+	writeBehindMaxPending int,
+	callWorkersCount int,
+	rulesPath string,
+	authenticationMode string,
+	credentialsPath string,
 	debug bool,
 ) {
 	routerShared.PrintLogotype()
@@ -55,24 +116,79 @@ func Run(
 	)
 	logger := slog.New(handler)
 
-	storage, e := routerStorages.NewBoltDBStorage(storagePath)
+	storage, e := routerStorages.NewStorage(storageClass, storagePath)
 	if e != nil {
-		logger.Error("during initialization of storage", "error", e)
+		logger.Error(
+			"during initialization of storage",
+			"error", e,
+			"storageClass", storageClass,
+			"availableStorageClasses", routerStorages.StorageClasses(),
+		)
 		panic("failed to initialize storage")
 	}
+	if len(routerID) == 0 {
+		routerID, e = ReadRouterID(storage)
+		if e != nil {
+			logger.Error("during read router id", "error", e)
+			panic("failed to initialize router id")
+		}
+	}
+	if writeBehindInterval > 0 {
+		storage = routerStorages.NewWriteBehindStorage(
+			storage,
+			writeBehindInterval,
+			writeBehindMaxPending,
+			logger,
+		)
+	}
 
 	keyRing := ReadKeyPair(privateKeyPath, logger)
 
+	authorizer, e := ReadAuthorizer(rulesPath, logger)
+	if e != nil {
+		logger.Error("during read rules", "error", e, "rulesPath", rulesPath)
+		panic("failed to initialize authorizer")
+	}
+	if authorizer != nil {
+		go reloadRulesOnHangup(authorizer, rulesPath, logger)
+	}
+
 	__router := router.NewRouter(
-		wampShared.NewID(),
+		routerID,
 		storage,
 		keyRing,
+		authorizer,
+		callWorkersCount,
 		logger,
 	)
+	authenticator, e := routerServers.NewAuthenticator(
+		authenticationMode,
+		credentialsPath,
+		__router.Session,
+		logger,
+	)
+	if e != nil {
+		logger.Error(
+			"during initialization of authenticator",
+			"error", e,
+			"authenticationMode", authenticationMode,
+			"availableAuthenticationModes", routerServers.AuthenticationModes(),
+			"credentialsPath", credentialsPath,
+		)
+		panic("failed to initialize authenticator")
+	}
+	if authenticationMode == routerServers.AUTHENTICATION_MODE_OPEN {
+		logger.Warn(
+			"everyone is admitted until `wamp.authenticate` is registered, use dynamic or static authentication mode to reject them",
+			"authenticationMode", authenticationMode,
+		)
+	}
+
 	http2server := routerServers.NewHTTP2Server(
 		http2address,
 		enableWebsocket,
 		__router,
+		authenticator,
 		logger,
 	)
 	unixServer := routerServers.NewUnixServer(
@@ -83,6 +199,7 @@ func Run(
 	go http2server.Serve()
 	go unixServer.Serve()
 	go __router.Serve()
+	__router.Reconcile(gracePeriod)
 
 	exitSignal := make(chan os.Signal, 1)
 	signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
@@ -97,15 +214,22 @@ func Run(
 }
 
 var (
-	routerIDFlag        *string
-	http2addressFlag    *string
-	enableWebsocketFlag *bool
-	unixPathFlag        *string
-	storageClassFlag    *string
-	storagePathFlag     *string
-	privateKeyPathFlag  *string
-	debugFlag           *bool
-	Command             = &cobra.Command{
+	routerIDFlag              *string
+	http2addressFlag          *string
+	enableWebsocketFlag       *bool
+	unixPathFlag              *string
+	storageClassFlag          *string
+	storagePathFlag           *string
+	privateKeyPathFlag        *string
+	gracePeriodFlag           *time.Duration
+	writeBehindIntervalFlag   *time.Duration
+	writeBehindMaxPendingFlag *int
+	callWorkersCountFlag      *int
+	rulesPathFlag             *string
+	authenticationModeFlag    *string
+	credentialsPathFlag       *string
+	debugFlag                 *bool
+	Command                   = &cobra.Command{
 		Use:   "run",
 		Short: "Run new instance of Router",
 		Run: func(cmd *cobra.Command, args []string) {
@@ -117,6 +241,13 @@ var (
 				*storageClassFlag,
 				*storagePathFlag,
 				*privateKeyPathFlag,
+				*gracePeriodFlag,
+				*writeBehindIntervalFlag,
+				*writeBehindMaxPendingFlag,
+				*callWorkersCountFlag,
+				*rulesPathFlag,
+				*authenticationModeFlag,
+				*credentialsPathFlag,
 				*debugFlag,
 			)
 		},
@@ -124,16 +255,22 @@ var (
 )
 
 func init() {
-	defaultRouterID := wampShared.NewID()
-	defaultUnixPath := "/tmp/wamp3rd-" + defaultRouterID + ".socket"
-	defaultStoragePath := "/tmp/wamp3rd-" + defaultRouterID + ".db"
+	defaultUnixPath := "/tmp/wamp3rd.socket"
+	defaultStoragePath := "/tmp/wamp3rd.db"
 	defaultPrivateKeyPath := "/tmp/wamp3rd.pem"
-	routerIDFlag = Command.Flags().String("id", defaultRouterID, "router id")
+	routerIDFlag = Command.Flags().String("id", "", "router id (empty means id kept in storage, generated on first run)")
 	http2addressFlag = Command.Flags().String("http2address", ":8800", "http2 address")
 	enableWebsocketFlag = Command.Flags().Bool("websocket", true, "enable websocket")
 	unixPathFlag = Command.Flags().String("unix-path", defaultUnixPath, "unix socket path")
-	storageClassFlag = Command.Flags().String("storage-class", "BoltDB", "storage class")
+	storageClassFlag = Command.Flags().String("storage-class", "BoltDB", "storage class (BoltDB, SQLite, Memory)")
 	storagePathFlag = Command.Flags().String("storage-path", defaultStoragePath, "storage path")
 	privateKeyPathFlag = Command.Flags().String("private-key-path", defaultPrivateKeyPath, "rsa private key path in pem format")
+	gracePeriodFlag = Command.Flags().Duration("grace-period", time.Minute, "time given to peers to rejoin before restored resources are removed")
+	writeBehindIntervalFlag = Command.Flags().Duration("write-behind-interval", 0, "batch storage updates and flush them with this interval (0 disables)")
+	writeBehindMaxPendingFlag = Command.Flags().Int("write-behind-max-pending", 1024, "flush storage updates when this many records are pending")
+	callWorkersCountFlag = Command.Flags().Int("call-workers", router.DEFAULT_CALL_WORKERS_COUNT, "number of calls of single peer processed at once")
+	rulesPathFlag = Command.Flags().String("rules-path", "", "json file with permissions of roles, reloaded on SIGHUP (empty allows everything)")
+	authenticationModeFlag = Command.Flags().String("authentication-mode", routerServers.AUTHENTICATION_MODE_DYNAMIC, "authentication mode (open allows everyone if wamp.authenticate is missing, dynamic requires it, static checks credentials file)")
+	credentialsPathFlag = Command.Flags().String("credentials-path", "", "json file with credentials of peers, used by static authentication mode")
 	debugFlag = Command.Flags().Bool("debug", false, "enable debug")
 }
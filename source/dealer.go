@@ -1,8 +1,12 @@
 package router
 
 import (
+	"errors"
 	"log/slog"
+	"math/rand"
+	"slices"
 	"sort"
+	"sync"
 	"time"
 
 	cmap "github.com/orcaman/concurrent-map/v2"
@@ -12,54 +16,348 @@ import (
 	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
-type RegistrationList = routerShared.ResourceList[*wamp.RegisterOptions]
+type RegistrationList = routerShared.ResourceList[*routerShared.RegisterOptions]
+
+var (
+	ErrorInvalidInvocationPolicy  = errors.New("InvalidInvocationPolicy")
+	ErrorProcedureAlreadyExists   = errors.New("ProcedureAlreadyExists")
+	ErrorInvocationPolicyConflict = errors.New("ProcedureExistsWithDifferentInvocationPolicy")
+	ErrorExecutorGone             = errors.New("ExecutorGone")
+	ErrorInvalidWeight            = errors.New("InvalidWeight")
+	ErrorInvalidCancelMode        = errors.New("InvalidCancelMode")
+	ErrorInvalidGeneratorTimeout  = errors.New("InvalidGeneratorTimeout")
+)
+
+// number of calls of single peer which are processed at once by default
+const DEFAULT_CALL_WORKERS_COUNT = 64
 
 type Dealer struct {
 	routerID      string
-	peers         map[string]*wamp.Peer
-	counter       cmap.ConcurrentMap[string, int]
-	registrations *routerShared.URIM[*wamp.RegisterOptions]
+	workersCount  int
+	peers         cmap.ConcurrentMap[string, *wamp.Peer]
+	departures    cmap.ConcurrentMap[string, chan struct{}]
+	rotations     cmap.ConcurrentMap[string, *weightedRoundRobin]
+	inflight      cmap.ConcurrentMap[string, int]
+	generators    cmap.ConcurrentMap[string, *Referee]
+	vacancyMutex  sync.Mutex
+	vacant        chan struct{}
+	registerMutex sync.Mutex
+	registrations *routerShared.URIM[*routerShared.RegisterOptions]
+	authorizer    *routerShared.Authorizer
 	logger        *slog.Logger
 }
 
 func NewDealer(
 	routerID string,
 	storage routerShared.Storage,
+	authorizer *routerShared.Authorizer,
+	workersCount int,
 	logger *slog.Logger,
 ) *Dealer {
+	if workersCount < 1 {
+		workersCount = DEFAULT_CALL_WORKERS_COUNT
+	}
 	return &Dealer{
 		routerID,
-		make(map[string]*wamp.Peer),
+		workersCount,
+		cmap.New[*wamp.Peer](),
+		cmap.New[chan struct{}](),
+		cmap.New[*weightedRoundRobin](),
 		cmap.New[int](),
-		routerShared.NewURIM[*wamp.RegisterOptions](storage, logger),
+		cmap.New[*Referee](),
+		sync.Mutex{},
+		make(chan struct{}),
+		sync.Mutex{},
+		routerShared.NewURIM[*routerShared.RegisterOptions]("registrations", storage, logger),
+		authorizer,
 		logger.With("name", "Dealer"),
 	}
 }
 
-func shift[T any](items []T, x int) []T {
-	return append(items[x:], items[:x]...)
+// registrations of the same pattern share invocation policy
+func registrationPattern(registration *routerShared.Registration) string {
+	return registration.Options.MatchPolicy() + ":" + registration.URI
 }
 
-func (dealer *Dealer) matchRegistrations(
-	uri string,
+// adds registration if it is compatible with invocation policy of the pattern
+func (dealer *Dealer) register(registration *routerShared.Registration) error {
+	policy := registration.Options.InvocationPolicy()
+	if !routerShared.ValidInvocationPolicy(policy) {
+		return ErrorInvalidInvocationPolicy
+	}
+	if registration.Options.Weight < 0 {
+		return ErrorInvalidWeight
+	}
+	if !routerShared.ValidCancelMode(registration.Options.CancelMode()) {
+		return ErrorInvalidCancelMode
+	}
+	if registration.Options.Lifetime < 0 || registration.Options.IdleTimeout < 0 {
+		return ErrorInvalidGeneratorTimeout
+	}
+
+	dealer.registerMutex.Lock()
+	defer dealer.registerMutex.Unlock()
+
+	registrationList, e := dealer.registrations.Lookup(registration.URI, registration.Options.MatchPolicy())
+	if e != nil {
+		return e
+	}
+	// restored registrations of authors which have not rejoined yet are ignored,
+	// author replaces its own registrations
+	own := RegistrationList{}
+	others := RegistrationList{}
+	for _, existing := range registrationList {
+		if existing.AuthorID == registration.AuthorID {
+			own = append(own, existing)
+		} else if dealer.peers.Has(existing.AuthorID) {
+			others = append(others, existing)
+		}
+	}
+	if len(others) > 0 {
+		if policy == routerShared.INVOKE_SINGLE {
+			return ErrorProcedureAlreadyExists
+		}
+		if others[0].Options.InvocationPolicy() != policy {
+			return ErrorInvocationPolicyConflict
+		}
+	}
+	for _, existing := range own {
+		if policy == routerShared.INVOKE_SINGLE || existing.Options.InvocationPolicy() != policy {
+			_, e = dealer.registrations.DeleteByAuthor(existing.AuthorID, existing.ID)
+			if e != nil {
+				return e
+			}
+			dealer.logger.Debug("registration replaced", "ID", existing.ID, "AuthorID", existing.AuthorID)
+		}
+	}
+
+	e = dealer.registrations.Add(registration)
+	if e == nil {
+		// queued calls may be dispatched to new registration
+		dealer.notifyVacancy()
+	}
+	return e
+}
+
+// returns false if executor already handles `limit` calls, zero `limit` means unlimited
+func (dealer *Dealer) acquire(executorID string, limit int) bool {
+	acquired := true
+	dealer.inflight.Upsert(
+		executorID,
+		1,
+		func(exist bool, count int, initial int) int {
+			if limit > 0 && count >= limit {
+				acquired = false
+				return count
+			}
+			return count + initial
+		},
+	)
+	return acquired
+}
+
+func (dealer *Dealer) release(executorID string) {
+	dealer.inflight.Upsert(
+		executorID,
+		0,
+		func(exist bool, count int, initial int) int {
+			return max(count-1, initial)
+		},
+	)
+	dealer.inflight.RemoveCb(
+		executorID,
+		func(key string, count int, exists bool) bool {
+			return exists && count == 0
+		},
+	)
+	dealer.notifyVacancy()
+}
+
+// returns channel which is closed when any executor is released
+func (dealer *Dealer) vacancy() <-chan struct{} {
+	dealer.vacancyMutex.Lock()
+	defer dealer.vacancyMutex.Unlock()
+	return dealer.vacant
+}
+
+// wakes up queued calls
+func (dealer *Dealer) notifyVacancy() {
+	dealer.vacancyMutex.Lock()
+	defer dealer.vacancyMutex.Unlock()
+	close(dealer.vacant)
+	dealer.vacant = make(chan struct{})
+}
+
+// returns number of calls which executor is currently handling, whatever procedures they are
+func (dealer *Dealer) inflightCount(executorID string) int {
+	count, _ := dealer.inflight.Get(executorID)
+	return count
+}
+
+// returns number of calls in flight of executor, or share of its capacity in use if `relative`,
+// executors without concurrency limit are loaded by number of calls in flight
+func (dealer *Dealer) load(registration *routerShared.Registration, relative bool) float64 {
+	count := float64(dealer.inflightCount(registration.AuthorID))
+	limit := registration.Options.Concurrency
+	if relative && limit > 0 {
+		return count / float64(limit)
+	}
+	return count
+}
+
+// smooth weighted round robin state of pattern,
+// registrations are chosen proportionally to weight and evenly spread in time
+type weightedRoundRobin struct {
+	mutex   sync.Mutex
+	current map[string]int
+}
+
+func newWeightedRoundRobin() *weightedRoundRobin {
+	return &weightedRoundRobin{current: make(map[string]int)}
+}
+
+// moves chosen registration to the beginning of list
+func (wrr *weightedRoundRobin) next(registrationList RegistrationList) RegistrationList {
+	wrr.mutex.Lock()
+	defer wrr.mutex.Unlock()
+
+	current := make(map[string]int, len(registrationList))
+	total := 0
+	chosen := 0
+	for i, registration := range registrationList {
+		weight := registration.Options.DistributionWeight()
+		current[registration.ID] = wrr.current[registration.ID] + weight
+		total += weight
+		if current[registration.ID] > current[registrationList[chosen].ID] {
+			chosen = i
+		}
+	}
+	current[registrationList[chosen].ID] -= total
+	// state of gone registrations is dropped
+	wrr.current = current
+
+	result := RegistrationList{registrationList[chosen]}
+	result = append(result, registrationList[:chosen]...)
+	result = append(result, registrationList[chosen+1:]...)
+	return result
+}
+
+// orders registrations of the same pattern according to invocation policy,
+// the first one is called, the others are used if dispatch fails
+func (dealer *Dealer) invocationOrder(
+	pattern string,
+	registrationList RegistrationList,
 ) RegistrationList {
-	registrationList := dealer.registrations.Match(uri)
+	// earliest registration comes first, because identifiers grow with time
+	sort.SliceStable(
+		registrationList,
+		func(i, j int) bool {
+			return registrationList[i].ID < registrationList[j].ID
+		},
+	)
+	sort.SliceStable(
+		registrationList,
+		func(i, j int) bool {
+			return registrationList[i].Options.Distance() > registrationList[j].Options.Distance()
+		},
+	)
 
-	n := len(registrationList)
-	if n > 0 {
-		sort.Slice(
+	policy := registrationList[0].Options.InvocationPolicy()
+	switch policy {
+	case routerShared.INVOKE_LAST:
+		slices.Reverse(registrationList)
+	case routerShared.INVOKE_RANDOM:
+		rand.Shuffle(
+			len(registrationList),
+			func(i, j int) {
+				registrationList[i], registrationList[j] = registrationList[j], registrationList[i]
+			},
+		)
+	case routerShared.INVOKE_LEASTBUSY, routerShared.INVOKE_LEASTLOADED:
+		// least busy compares calls in flight, least loaded compares them with concurrency limit
+		relative := policy == routerShared.INVOKE_LEASTLOADED
+		sort.SliceStable(
 			registrationList,
 			func(i, j int) bool {
-				return registrationList[i].Options.Distance() > registrationList[j].Options.Distance()
+				return dealer.load(registrationList[i], relative) < dealer.load(registrationList[j], relative)
 			},
 		)
+	case routerShared.INVOKE_ROUNDROBIN:
+		wrr := dealer.rotations.Upsert(
+			pattern,
+			nil,
+			func(exist bool, wrr *weightedRoundRobin, __ *weightedRoundRobin) *weightedRoundRobin {
+				if exist {
+					return wrr
+				}
+				return newWeightedRoundRobin()
+			},
+		)
+		registrationList = wrr.next(registrationList)
+	}
+	return registrationList
+}
+
+// returns true if pattern `a` is more specific than pattern `b`
+func morePrecise(a *routerShared.Registration, b *routerShared.Registration) bool {
+	precedence := map[string]int{
+		routerShared.MATCH_EXACT:    0,
+		routerShared.MATCH_PREFIX:   1,
+		routerShared.MATCH_WILDCARD: 2,
+		routerShared.MATCH_REGEX:    3,
+	}
+	aPrecedence := precedence[a.Options.MatchPolicy()]
+	bPrecedence := precedence[b.Options.MatchPolicy()]
+	if aPrecedence != bPrecedence {
+		return aPrecedence < bPrecedence
+	}
+	// longer prefix is more specific
+	return len(a.URI) > len(b.URI)
+}
 
-		count, _ := dealer.counter.Get(uri)
-		offset := count % n
-		registrationList = shift(registrationList, offset)
-		dealer.counter.Set(uri, count + 1)
+// returns registrations which patterns are the URI itself, i.e. exact ones and wildcard ones without wildcards
+func (dealer *Dealer) literalRegistrations(
+	uri string,
+) RegistrationList {
+	registrationList := RegistrationList{}
+	for _, policy := range []string{routerShared.MATCH_EXACT, routerShared.MATCH_WILDCARD} {
+		found, _ := dealer.registrations.Lookup(uri, policy)
+		registrationList = append(registrationList, found...)
 	}
+	return registrationList
+}
+
+func (dealer *Dealer) matchRegistrations(
+	uri string,
+) RegistrationList {
+	candidates := dealer.registrations.Match(uri)
+	if uri == routerShared.AUTHORIZE_URI {
+		// hook decides for everyone, so it must not be served by patterns
+		candidates = dealer.literalRegistrations(uri)
+	}
+
+	groups := map[string]RegistrationList{}
+	patterns := []string{}
+	for _, registration := range candidates {
+		pattern := registrationPattern(registration)
+		_, found := groups[pattern]
+		if !found {
+			patterns = append(patterns, pattern)
+		}
+		groups[pattern] = append(groups[pattern], registration)
+	}
+
+	sort.Slice(
+		patterns,
+		func(i, j int) bool {
+			return morePrecise(groups[patterns[i]][0], groups[patterns[j]][0])
+		},
+	)
 
+	registrationList := RegistrationList{}
+	for _, pattern := range patterns {
+		registrationList = append(registrationList, dealer.invocationOrder(pattern, groups[pattern])...)
+	}
 	return registrationList
 }
 
@@ -85,6 +383,122 @@ func (dealer *Dealer) sendReply(
 	}
 }
 
+// returns nil channel if timeout is not set, so waiting on it blocks forever
+func after(timeout time.Duration) <-chan time.Time {
+	if timeout > 0 {
+		return time.After(timeout)
+	}
+	return nil
+}
+
+// returns channel which fires at deadline, nil channel if deadline is not set
+func until(deadline time.Time) <-chan time.Time {
+	if deadline.IsZero() {
+		return nil
+	}
+	return time.After(time.Until(deadline))
+}
+
+// returns true if departure channel is closed
+func departed(departure <-chan struct{}) bool {
+	select {
+	case <-departure:
+		return true
+	default:
+		return false
+	}
+}
+
+// returns channel which is closed when peer leaves
+func (dealer *Dealer) departure(peerID string) <-chan struct{} {
+	departure, found := dealer.departures.Get(peerID)
+	if !found {
+		// peer has gone already
+		departure = make(chan struct{})
+		close(departure)
+	}
+	return departure
+}
+
+// asks executor to stop invocation which is not awaited anymore
+func (dealer *Dealer) cancelInvocation(executor *wamp.Peer, callEvent wamp.CallEvent) {
+	cancelEvent := wamp.MakeCancelEvent(
+		wampShared.NewID(),
+		&wamp.ReplyFeatures{InvocationID: callEvent.ID(), VisitedRouters: []string{dealer.routerID}},
+	)
+	ok := executor.Send(cancelEvent, wamp.DEFAULT_RESEND_COUNT)
+	if !ok {
+		dealer.logger.Error("cancel event dispatch error", "InvocationID", callEvent.ID(), "ExecutorID", executor.ID)
+	}
+}
+
+// handles cancellation of dispatched call according to cancel mode of registration
+func (dealer *Dealer) onCancel(
+	caller *wamp.Peer,
+	executor *wamp.Peer,
+	registration *routerShared.Registration,
+	callEvent wamp.CallEvent,
+	cancelEvent wamp.CancelEvent,
+	replyEventPromise wampShared.Promise[wamp.ReplyEvent],
+	cancelReplyEventPromise wampShared.CancelPromise,
+	deadline time.Time,
+) {
+	forwardCancelEvent := func() {
+		cancelFeatures := cancelEvent.Features()
+		cancelFeatures.VisitedRouters = append(cancelFeatures.VisitedRouters, dealer.routerID)
+		ok := executor.Send(cancelEvent, wamp.DEFAULT_RESEND_COUNT)
+		if !ok {
+			dealer.logger.Error("cancel event dispatch error", "InvocationID", callEvent.ID(), "ExecutorID", executor.ID)
+		}
+	}
+
+	switch registration.Options.CancelMode() {
+	case routerShared.CANCEL_SKIP:
+		// executor keeps working, so it stays busy until reply, but not longer than call may last
+		if deadline.IsZero() {
+			deadline = time.Now().Add(time.Duration(wamp.DEFAULT_TIMEOUT) * time.Second)
+		}
+		awaitExecutor := func() {
+			select {
+			case <-replyEventPromise:
+			case <-dealer.departure(executor.ID):
+				cancelReplyEventPromise()
+			case <-until(deadline):
+				cancelReplyEventPromise()
+			}
+			dealer.release(registration.AuthorID)
+		}
+		go awaitExecutor()
+
+		response := wamp.NewErrorEvent(callEvent, wamp.ErrorCancelled)
+		dealer.sendReply(caller, response)
+	case routerShared.CANCEL_KILL:
+		forwardCancelEvent()
+
+		select {
+		case response := <-replyEventPromise:
+			dealer.sendReply(caller, response)
+		case <-dealer.departure(executor.ID):
+			cancelReplyEventPromise()
+			response := wamp.NewErrorEvent(callEvent, ErrorExecutorGone)
+			dealer.sendReply(caller, response)
+		case <-until(deadline):
+			cancelReplyEventPromise()
+			response := wamp.NewErrorEvent(callEvent, wamp.ErrorTimedOut)
+			dealer.sendReply(caller, response)
+		}
+		dealer.release(registration.AuthorID)
+	default:
+		cancelReplyEventPromise()
+		dealer.release(registration.AuthorID)
+
+		forwardCancelEvent()
+
+		response := wamp.NewErrorEvent(callEvent, wamp.ErrorCancelled)
+		dealer.sendReply(caller, response)
+	}
+}
+
 func (dealer *Dealer) onCall(
 	caller *wamp.Peer,
 	callEvent wamp.CallEvent,
@@ -96,8 +510,21 @@ func (dealer *Dealer) onCall(
 	route.CallerID = caller.ID
 	route.VisitedRouters = append(route.VisitedRouters, dealer.routerID)
 
+	e := dealer.authorizer.Authorize(caller.ID, routerShared.ACTION_CALL, features.URI)
+	if e != nil {
+		response := wamp.NewErrorEvent(callEvent, e)
+		dealer.sendReply(caller, response)
+		return nil
+	}
+
+	// caller gives up after timeout, so queue and every attempt share single deadline
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
 	cancelCallEventPromise, cancelCancelEventPromise := caller.PendingCancelEvents.New(
-		callEvent.ID(), timeout,
+		callEvent.ID(), 0,
 	)
 
 	requestLogData := slog.Group(
@@ -110,84 +537,203 @@ func (dealer *Dealer) onCall(
 	)
 	dealer.logger.Debug("call", requestLogData)
 
-	registrationList := dealer.matchRegistrations(features.URI)
+	// registrations which have been tried already
+	attempted := routerShared.NewEmptySet[string]()
+	failovers := 0
+	replyError := wamp.ErrorProcedureNotFound
 
-	for _, registration := range registrationList {
-		registrationLogData := slog.Group(
-			"subscription",
-			"ID", registration.ID,
-			"URI", registration.URI,
-			"SubscriberID", registration.AuthorID,
-		)
+	for {
+		// must be taken before acquire, otherwise vacancy may be missed
+		vacancy := dealer.vacancy()
+		saturated := false
 
-		executor, exists := dealer.peers[registration.AuthorID]
-		if !exists {
-			dealer.logger.Error("invalid registartion (peer not found)", registrationLogData, requestLogData)
-			continue
-		}
+		registrationList := dealer.matchRegistrations(features.URI)
 
-		route.EndpointID = registration.ID
-		route.ExecutorID = executor.ID
+		for _, registration := range registrationList {
+			if attempted.Contains(registration.ID) {
+				continue
+			}
 
-		replyEventPromise, cancelReplyEventPromise := executor.PendingReplyEvents.New(callEvent.ID(), 0)
-		ok := executor.Send(callEvent, wamp.DEFAULT_RESEND_COUNT)
-		if !ok {
-			dealer.logger.Error("call event dispatch error", registrationLogData, requestLogData)
-			continue
-		}
-		dealer.logger.Debug("reply event sent", registrationLogData, requestLogData)
+			registrationLogData := slog.Group(
+				"subscription",
+				"ID", registration.ID,
+				"URI", registration.URI,
+				"SubscriberID", registration.AuthorID,
+			)
+
+			executor, exists := dealer.peers.Get(registration.AuthorID)
+			if !exists {
+				dealer.logger.Error("invalid registartion (peer not found)", registrationLogData, requestLogData)
+				continue
+			}
 
-		select {
-		case cancelEvent, done := <-cancelCallEventPromise:
-			cancelReplyEventPromise()
+			if !dealer.acquire(registration.AuthorID, registration.Options.Concurrency) {
+				dealer.logger.Debug("executor saturated", registrationLogData, requestLogData)
+				saturated = true
+				continue
+			}
+			attempted.Add(registration.ID)
+
+			// every attempt gets own route, because local peers share events
+			invocation := wamp.MakeCallEvent(
+				callEvent.ID(),
+				features,
+				callEvent.Payload(),
+				&wamp.CallRoute{
+					CallerID:       route.CallerID,
+					ExecutorID:     executor.ID,
+					EndpointID:     registration.ID,
+					VisitedRouters: route.VisitedRouters,
+				},
+			)
+
+			replyEventPromise, cancelReplyEventPromise := executor.PendingReplyEvents.New(callEvent.ID(), 0)
+			ok := executor.Send(invocation, wamp.DEFAULT_RESEND_COUNT)
+			if !ok {
+				cancelReplyEventPromise()
+				dealer.release(registration.AuthorID)
+				dealer.logger.Error("call event dispatch error", registrationLogData, requestLogData)
+				continue
+			}
+			dealer.logger.Debug("reply event sent", registrationLogData, requestLogData)
+
+			failover := failovers < registration.Options.Failover
+			// attempts which are left share time which is left
+			attemptDeadline := deadline
+			if failover && !deadline.IsZero() {
+				attemptsLeft := time.Duration(registration.Options.Failover - failovers + 1)
+				attemptDeadline = time.Now().Add(time.Until(deadline) / attemptsLeft)
+			}
 
-			if done {
-				cancelFeatures := cancelEvent.Features()
-				cancelFeatures.VisitedRouters = append(cancelFeatures.VisitedRouters, dealer.routerID)
-				ok := executor.Send(cancelEvent, wamp.DEFAULT_RESEND_COUNT)
-				if ok {
-					dealer.logger.Info("call event cancelled", registrationLogData, requestLogData)
-				} else {
-					dealer.logger.Error("call event dispatch error", registrationLogData, requestLogData)
+			select {
+			case cancelEvent := <-cancelCallEventPromise:
+				dealer.logger.Info(
+					"call event cancelled",
+					"mode", registration.Options.CancelMode(),
+					registrationLogData,
+					requestLogData,
+				)
+				dealer.onCancel(
+					caller,
+					executor,
+					registration,
+					callEvent,
+					cancelEvent,
+					replyEventPromise,
+					cancelReplyEventPromise,
+					deadline,
+				)
+			case <-until(attemptDeadline):
+				cancelReplyEventPromise()
+				dealer.release(registration.AuthorID)
+
+				if failover {
+					dealer.logger.Warn("call event timeout, trying next executor", registrationLogData, requestLogData)
+					dealer.cancelInvocation(executor, callEvent)
+					failovers++
+					replyError = wamp.ErrorTimedOut
+					continue
 				}
-			} else {
+
+				cancelCancelEventPromise()
 				dealer.logger.Debug("call event timeout", registrationLogData, requestLogData)
 
 				response := wamp.NewErrorEvent(callEvent, wamp.ErrorTimedOut)
 				dealer.sendReply(caller, response)
-			}
-		case response := <-replyEventPromise:
-			cancelCancelEventPromise()
+			case <-dealer.departure(executor.ID):
+				cancelReplyEventPromise()
+				dealer.release(registration.AuthorID)
+
+				if failover {
+					dealer.logger.Warn("executor gone, trying next executor", registrationLogData, requestLogData)
+					failovers++
+					replyError = ErrorExecutorGone
+					continue
+				}
+
+				cancelCancelEventPromise()
+				dealer.logger.Debug("executor gone", registrationLogData, requestLogData)
 
-			if response.Kind() == wamp.MK_YIELD {
-				loopGenerator(dealer.routerID, caller, executor, callEvent, response, dealer.logger)
-			} else {
+				response := wamp.NewErrorEvent(callEvent, ErrorExecutorGone)
 				dealer.sendReply(caller, response)
+			case response := <-replyEventPromise:
+				cancelCancelEventPromise()
+				dealer.release(registration.AuthorID)
+
+				if response.Kind() == wamp.MK_YIELD {
+					dealer.loopGenerator(caller, executor, invocation, response, registration.Options)
+				} else {
+					dealer.sendReply(caller, response)
+				}
 			}
+
+			return nil
 		}
 
-		return nil
+		if !saturated {
+			break
+		}
+
+		// every executor is busy, call waits in queue until one of them is released
+		dealer.logger.Debug("call queued", requestLogData)
+		select {
+		case <-vacancy:
+		case <-cancelCallEventPromise:
+			dealer.logger.Info("queued call event cancelled", requestLogData)
+
+			response := wamp.NewErrorEvent(callEvent, wamp.ErrorCancelled)
+			dealer.sendReply(caller, response)
+			return nil
+		case <-until(deadline):
+			cancelCancelEventPromise()
+			dealer.logger.Debug("queued call event timeout", requestLogData)
+
+			response := wamp.NewErrorEvent(callEvent, wamp.ErrorTimedOut)
+			dealer.sendReply(caller, response)
+			return nil
+		}
 	}
 
 	cancelCancelEventPromise()
 
-	dealer.logger.Debug("procedure not found", requestLogData)
-	response := wamp.NewErrorEvent(callEvent, wamp.ErrorProcedureNotFound)
+	dealer.logger.Debug("procedure not found", "error", replyError, requestLogData)
+	response := wamp.NewErrorEvent(callEvent, replyError)
 	dealer.sendReply(caller, response)
 
 	return nil
 }
 
 func (dealer *Dealer) onLeave(peer *wamp.Peer) {
-	delete(dealer.peers, peer.ID)
+	dealer.peers.Remove(peer.ID)
+	departure, found := dealer.departures.Pop(peer.ID)
+	if found {
+		close(departure)
+	}
+	// queued calls must not wait for executor which has gone
+	dealer.notifyVacancy()
 	dealer.logger.Debug("dettach peer", "ID", peer.ID)
 }
 
 func (dealer *Dealer) onJoin(peer *wamp.Peer) {
 	dealer.logger.Debug("attach peer", "ID", peer.ID)
-	dealer.peers[peer.ID] = peer
+	dealer.peers.Set(peer.ID, peer)
+	departure := make(chan struct{})
+	dealer.departures.Set(peer.ID, departure)
+
+	// every call event arrives in own goroutine, so no more than `workersCount` of them proceed at once,
+	// generator occupies slot until it is done
+	slots := make(chan struct{}, dealer.workersCount)
 	peer.IncomingCallEvents.Observe(
-		func(event wamp.CallEvent) { dealer.onCall(peer, event) },
+		func(callEvent wamp.CallEvent) {
+			select {
+			case slots <- struct{}{}:
+			case <-departure:
+				dealer.logger.Debug("call event dropped (peer gone)", "ID", callEvent.ID(), "CallerID", peer.ID)
+				return
+			}
+			defer func() { <-slots }()
+			dealer.onCall(peer, callEvent)
+		},
 		func() { dealer.onLeave(peer) },
 	)
 }
@@ -1,51 +1,106 @@
 package router
 
 import (
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 
 	wamp "github.com/wamp3hub/wamp3go"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
 var (
-	SomethingWentWrong = errors.New("SomethingWentWrong")
+	SomethingWentWrong     = errors.New("SomethingWentWrong")
+	ErrorPeerAlreadyExists = errors.New("PeerAlreadyExists")
 )
 
+// converts payload to the required type regardless of transport,
+// local peers send go values while remote peers send json
+func decodePayload[T any](v any) (T, error) {
+	var payload T
+	raw, e := json.Marshal(v)
+	if e == nil {
+		e = json.Unmarshal(raw, &payload)
+	}
+	return payload, e
+}
+
 func mount[I, O any](
 	router *Router,
 	uri string,
-	options *wamp.RegisterOptions,
+	options *routerShared.RegisterOptions,
 	procedure wamp.ProcedureToCall[I, O],
 ) {
-	registration := wamp.Registration{
+	registration := routerShared.Registration{
 		ID:       wampShared.NewID(),
 		URI:      uri,
 		AuthorID: router.ID,
 		Options:  options,
 	}
 	router.Dealer.registrations.Add(&registration)
-	endpoint := wamp.NewCallEventEndpoint[I, O](procedure, router.logger)
+	endpoint := wamp.NewCallEventEndpoint[any, O](
+		func(v any, callEvent wamp.CallEvent) (O, error) {
+			payload, e := decodePayload[I](v)
+			if e != nil {
+				var __ O
+				return __, wamp.ErrorInvalidPayload
+			}
+			return procedure(payload, callEvent)
+		},
+		router.logger,
+	)
 	router.Session.Registrations[registration.ID] = endpoint
 }
 
 func (router *Router) intialize() {
-	mount(router, "wamp.router.register", &wamp.RegisterOptions{}, router.__register)
-	mount(router, "wamp.router.unregister", &wamp.RegisterOptions{}, router.__unregister)
-	mount(router, "wamp.router.registration.list", &wamp.RegisterOptions{}, router.__getRegistrationList)
-	mount(router, "wamp.router.subscribe", &wamp.RegisterOptions{}, router.__subscribe)
-	mount(router, "wamp.router.unsubscribe", &wamp.RegisterOptions{}, router.__unsubscribe)
-	mount(router, "wamp.router.subscription.list", &wamp.RegisterOptions{}, router.__getSubscriptionList)
+	mount(router, "wamp.router.register", &routerShared.RegisterOptions{}, router.__register)
+	mount(router, "wamp.router.unregister", &routerShared.RegisterOptions{}, router.__unregister)
+	mount(router, "wamp.router.registration.list", &routerShared.RegisterOptions{}, router.__getRegistrationList)
+	mount(router, "wamp.router.subscribe", &routerShared.RegisterOptions{}, router.__subscribe)
+	mount(router, "wamp.router.unsubscribe", &routerShared.RegisterOptions{}, router.__unsubscribe)
+	mount(router, "wamp.router.subscription.list", &routerShared.RegisterOptions{}, router.__getSubscriptionList)
+	mount(router, "wamp.router.generator.list", &routerShared.RegisterOptions{}, router.__getGeneratorList)
+	mount(router, "wamp.router.generator.stop", &routerShared.RegisterOptions{}, router.__stopGenerator)
+	mount(router, "wamp.ticket.generate", &routerShared.RegisterOptions{}, router.__generateTicket)
+}
+
+// converts registration to the form known by clients
+func exportRegistration(registration *routerShared.Registration) *wamp.Registration {
+	return &wamp.Registration{
+		ID:       registration.ID,
+		URI:      registration.URI,
+		AuthorID: registration.AuthorID,
+		Options:  &registration.Options.RegisterOptions,
+	}
+}
+
+// converts subscription to the form known by clients
+func exportSubscription(subscription *routerShared.Subscription) *wamp.Subscription {
+	return &wamp.Subscription{
+		ID:       subscription.ID,
+		URI:      subscription.URI,
+		AuthorID: subscription.AuthorID,
+		Options:  &subscription.Options.SubscribeOptions,
+	}
 }
 
 func (router *Router) __register(
-	payload wamp.NewResourcePayload[wamp.RegisterOptions],
+	payload wamp.NewResourcePayload[routerShared.RegisterOptions],
 	callEvent wamp.CallEvent,
 ) (*wamp.Registration, error) {
 	if len(payload.URI) == 0 {
 		return nil, wamp.ErrorInvalidPayload
 	}
 
+	if payload.Options == nil {
+		payload.Options = new(routerShared.RegisterOptions)
+	}
+
 	route := callEvent.Route()
 
 	logData := slog.Group(
@@ -54,15 +109,35 @@ func (router *Router) __register(
 		"AuthorID", route.CallerID,
 	)
 
-	registration := wamp.Registration{
+	e := router.Authorizer.AuthorizePattern(
+		route.CallerID,
+		routerShared.ACTION_REGISTER,
+		payload.URI,
+		payload.Options.MatchPolicy(),
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	registration := routerShared.Registration{
 		ID:       wampShared.NewID(),
 		URI:      payload.URI,
 		AuthorID: route.CallerID,
 		Options:  payload.Options,
 	}
 	payload.Options.Route = append(payload.Options.Route, router.ID)
-	e := router.Dealer.registrations.Add(&registration)
-	if e != nil {
+	e = router.Dealer.register(&registration)
+	if errors.Is(e, routerShared.ErrorInvalidURI) ||
+		errors.Is(e, routerShared.ErrorInvalidMatchPolicy) ||
+		errors.Is(e, ErrorInvalidInvocationPolicy) ||
+		errors.Is(e, ErrorInvalidWeight) ||
+		errors.Is(e, ErrorInvalidCancelMode) ||
+		errors.Is(e, ErrorInvalidGeneratorTimeout) ||
+		errors.Is(e, ErrorProcedureAlreadyExists) ||
+		errors.Is(e, ErrorInvocationPolicyConflict) {
+		router.logger.Warn("during add registration into URIM", "error", e, logData)
+		return nil, e
+	} else if e != nil {
 		router.logger.Error("during add registration into URIM", "error", e, logData)
 		return nil, SomethingWentWrong
 	}
@@ -73,7 +148,7 @@ func (router *Router) __register(
 			URI:     "wamp.registration.new",
 			Exclude: []string{registration.AuthorID},
 		},
-		registration,
+		exportRegistration(&registration),
 	)
 	if e == nil {
 		router.logger.Info("new registeration", logData)
@@ -83,14 +158,27 @@ func (router *Router) __register(
 		)
 	}
 
-	return &registration, nil
+	return exportRegistration(&registration), nil
 }
 
 func (router *Router) unregister(
 	authorID string,
 	registrationID string,
-) {
-	removedRegistrationList := router.Dealer.registrations.DeleteByAuthor(authorID, registrationID)
+) error {
+	removedRegistrationList, e := router.Dealer.registrations.DeleteByAuthor(authorID, registrationID)
+	if e != nil {
+		router.logger.Error(
+			"during delete registration from URIM",
+			"error", e,
+			"AuthorID", authorID,
+			"RegistrationID", registrationID,
+		)
+		return e
+	}
+
+	// queued calls must not wait for registrations which have gone
+	router.Dealer.notifyVacancy()
+
 	for _, registration := range removedRegistrationList {
 		logData := slog.Group(
 			"registration",
@@ -112,6 +200,8 @@ func (router *Router) unregister(
 			router.logger.Error("during publish to topic 'wamp.registration.gone'", logData)
 		}
 	}
+
+	return nil
 }
 
 func (router *Router) __unregister(
@@ -123,7 +213,10 @@ func (router *Router) __unregister(
 	}
 
 	route := callEvent.Route()
-	router.unregister(route.CallerID, registrationID)
+	e := router.unregister(route.CallerID, registrationID)
+	if e != nil {
+		return struct{}{}, SomethingWentWrong
+	}
 
 	return struct{}{}, nil
 }
@@ -133,20 +226,22 @@ func (router *Router) __getRegistrationList(
 	callEvent wamp.CallEvent,
 ) (*RegistrationList, error) {
 	source := wamp.Event(callEvent)
-	URIList := router.Dealer.registrations.DumpURIList()
-	for _, uri := range URIList {
-		registrationList := router.Dealer.registrations.Match(uri)
+	for _, registrationList := range router.Dealer.registrations.Dump() {
 		source = wamp.Yield(source, registrationList)
 	}
 	return nil, wamp.GeneratorExit(source)
 }
 
 func (router *Router) __subscribe(
-	payload wamp.NewResourcePayload[wamp.SubscribeOptions],
+	payload wamp.NewResourcePayload[routerShared.SubscribeOptions],
 	callEvent wamp.CallEvent,
 ) (*wamp.Subscription, error) {
 	if len(payload.URI) == 0 {
-		return nil, errors.New("InvalidURI")
+		return nil, routerShared.ErrorInvalidURI
+	}
+
+	if payload.Options == nil {
+		payload.Options = new(routerShared.SubscribeOptions)
 	}
 
 	route := callEvent.Route()
@@ -157,15 +252,28 @@ func (router *Router) __subscribe(
 		"AuthorID", route.CallerID,
 	)
 
-	subscription := wamp.Subscription{
+	e := router.Authorizer.AuthorizePattern(
+		route.CallerID,
+		routerShared.ACTION_SUBSCRIBE,
+		payload.URI,
+		payload.Options.MatchPolicy(),
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	subscription := routerShared.Subscription{
 		ID:       wampShared.NewID(),
 		URI:      payload.URI,
 		AuthorID: route.CallerID,
 		Options:  payload.Options,
 	}
 	subscription.Options.Route = append(subscription.Options.Route, router.ID)
-	e := router.Broker.subscriptions.Add(&subscription)
-	if e != nil {
+	e = router.Broker.subscriptions.Add(&subscription)
+	if errors.Is(e, routerShared.ErrorInvalidURI) || errors.Is(e, routerShared.ErrorInvalidMatchPolicy) {
+		router.logger.Warn("during add subscription into URIM", "error", e, logData)
+		return nil, e
+	} else if e != nil {
 		router.logger.Error("during add subscription into URIM", "error", e, logData)
 		return nil, SomethingWentWrong
 	}
@@ -176,7 +284,7 @@ func (router *Router) __subscribe(
 			URI:     "wamp.subscription.new",
 			Exclude: []string{subscription.AuthorID},
 		},
-		subscription,
+		exportSubscription(&subscription),
 	)
 	if e == nil {
 		router.logger.Info("new subscription", logData)
@@ -184,14 +292,24 @@ func (router *Router) __subscribe(
 		router.logger.Error("during publish to 'wamp.subscription.new'", "error", e, logData)
 	}
 
-	return &subscription, nil
+	return exportSubscription(&subscription), nil
 }
 
 func (router *Router) unsubscribe(
 	authorID string,
 	subscriptionID string,
-) {
-	removedSubscriptionList := router.Broker.subscriptions.DeleteByAuthor(authorID, subscriptionID)
+) error {
+	removedSubscriptionList, e := router.Broker.subscriptions.DeleteByAuthor(authorID, subscriptionID)
+	if e != nil {
+		router.logger.Error(
+			"during delete subscription from URIM",
+			"error", e,
+			"AuthorID", authorID,
+			"SubscriptionID", subscriptionID,
+		)
+		return e
+	}
+
 	for _, subscription := range removedSubscriptionList {
 		logData := slog.Group(
 			"subscription",
@@ -213,6 +331,8 @@ func (router *Router) unsubscribe(
 			router.logger.Error("during publish to 'wamp.subscription.gone'", logData)
 		}
 	}
+
+	return nil
 }
 
 func (router *Router) __unsubscribe(
@@ -224,7 +344,10 @@ func (router *Router) __unsubscribe(
 	}
 
 	route := callEvent.Route()
-	router.unsubscribe(route.CallerID, subscriptionID)
+	e := router.unsubscribe(route.CallerID, subscriptionID)
+	if e != nil {
+		return struct{}{}, SomethingWentWrong
+	}
 
 	return struct{}{}, nil
 }
@@ -234,10 +357,123 @@ func (router *Router) __getSubscriptionList(
 	callEvent wamp.CallEvent,
 ) (*SubscriptionList, error) {
 	source := wamp.Event(callEvent)
-	URIList := router.Broker.subscriptions.DumpURIList()
-	for _, uri := range URIList {
-		subscriptionList := router.Broker.subscriptions.Match(uri)
+	for _, subscriptionList := range router.Broker.subscriptions.Dump() {
 		source = wamp.Yield(source, subscriptionList)
 	}
 	return nil, wamp.GeneratorExit(source)
 }
+
+// lists generators of every peer, only peers trusted by transport may do so
+func (router *Router) __getGeneratorList(
+	payload any,
+	callEvent wamp.CallEvent,
+) (*GeneratorInfo, error) {
+	route := callEvent.Route()
+	if !router.Authorizer.Trusted(route.CallerID) {
+		router.logger.Warn("generator listing by untrusted peer", "CallerID", route.CallerID)
+		return nil, routerShared.ErrorNotAuthorized
+	}
+
+	source := wamp.Event(callEvent)
+	for _, generator := range router.Dealer.generatorList() {
+		source = wamp.Yield(source, generator)
+	}
+	return nil, wamp.GeneratorExit(source)
+}
+
+// stops generator of any peer, only peers trusted by transport may do so
+func (router *Router) __stopGenerator(
+	generatorID string,
+	callEvent wamp.CallEvent,
+) (struct{}, error) {
+	route := callEvent.Route()
+	if !router.Authorizer.Trusted(route.CallerID) {
+		router.logger.Warn("generator stop by untrusted peer", "CallerID", route.CallerID)
+		return struct{}{}, routerShared.ErrorNotAuthorized
+	}
+
+	if len(generatorID) == 0 {
+		return struct{}{}, wamp.ErrorInvalidPayload
+	}
+
+	e := router.Dealer.stopGenerator(generatorID)
+	if e != nil {
+		return struct{}{}, e
+	}
+
+	router.logger.Info("generator stopped", "GeneratorID", generatorID)
+	return struct{}{}, nil
+}
+
+// asks peer which has registered `wamp.authorize`
+func (router *Router) callAuthorizationHook(
+	request routerShared.AuthorizationRequest,
+) (bool, error) {
+	if len(router.Dealer.literalRegistrations(routerShared.AUTHORIZE_URI)) == 0 {
+		return false, routerShared.ErrorHookUnavailable
+	}
+
+	pendingResponse := wamp.Call[bool](
+		router.Session,
+		&wamp.CallFeatures{URI: routerShared.AUTHORIZE_URI, Timeout: wamp.DEFAULT_TIMEOUT},
+		request,
+	)
+	_, allowed, e := pendingResponse.Await()
+	if e != nil && e.Error() == wamp.ErrorProcedureNotFound.Error() {
+		return false, routerShared.ErrorHookUnavailable
+	}
+	return allowed, e
+}
+
+type GenerateTicketPayload struct {
+	PeerID   string        `json:"peerID"`
+	Duration time.Duration `json:"duration"`
+	Role     string        `json:"role"`
+}
+
+// signs ticket for peer, only peers trusted by transport may do so
+func (router *Router) __generateTicket(
+	payload GenerateTicketPayload,
+	callEvent wamp.CallEvent,
+) (string, error) {
+	route := callEvent.Route()
+	if !router.Authorizer.Trusted(route.CallerID) {
+		router.logger.Warn("ticket generation by untrusted peer", "CallerID", route.CallerID)
+		return "", routerShared.ErrorNotAuthorized
+	}
+
+	if len(payload.PeerID) == 0 || payload.Duration <= 0 {
+		return "", wamp.ErrorInvalidPayload
+	}
+
+	if payload.Role == routerShared.ROLE_LOCAL {
+		return "", routerShared.ErrorReservedRole
+	}
+
+	// ticket must not let peer take place of router or another peer
+	if payload.PeerID == router.ID ||
+		router.Dealer.peers.Has(payload.PeerID) ||
+		router.Broker.peers.Has(payload.PeerID) {
+		router.logger.Warn("ticket generation for taken peer id", "PeerID", payload.PeerID)
+		return "", ErrorPeerAlreadyExists
+	}
+
+	now := time.Now()
+	claims := routerShared.JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    router.ID,
+			Subject:   payload.PeerID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(payload.Duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Role: payload.Role,
+	}
+	ticket, e := router.KeyRing.JWTSign(&claims)
+	if e != nil {
+		router.logger.Error("during sign ticket", "error", e)
+		return "", SomethingWentWrong
+	}
+
+	router.logger.Info("ticket generated", "PeerID", payload.PeerID, "Role", payload.Role, "Duration", payload.Duration)
+	return ticket, nil
+}
@@ -1,23 +1,80 @@
 package router
 
 import (
+	"errors"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	wamp "github.com/wamp3hub/wamp3go"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
+var (
+	ErrorGeneratorExpired  = errors.New("GeneratorExpired")
+	ErrorGeneratorIdle     = errors.New("GeneratorIdle")
+	ErrorGeneratorStopped  = errors.New("GeneratorStopped")
+	ErrorGeneratorNotFound = errors.New("GeneratorNotFound")
+)
+
+type GeneratorInfo struct {
+	ID         string `json:"ID"`
+	URI        string `json:"URI"`
+	CallerID   string `json:"callerID"`
+	ExecutorID string `json:"executorID"`
+	// seconds since generator has been created
+	Age uint64 `json:"age"`
+	// number of next requests answered
+	Rounds int64 `json:"rounds"`
+}
+
 type Referee struct {
 	ID               string
+	URI              string
 	routerID         string
 	caller           *wamp.Peer
 	executor         *wamp.Peer
 	logger           *slog.Logger
 	stopEventPromise wampShared.Promise[wamp.StopEvent]
+	idleTimeout      time.Duration
+	// closed when peer leaves router
+	callerDeparture   <-chan struct{}
+	executorDeparture <-chan struct{}
+	createdAt         time.Time
+	rounds            atomic.Int64
+	// closed when generator expires or is stopped by router
+	terminated    chan struct{}
+	terminateOnce sync.Once
+	reason        error
+}
+
+// ends generator for `reason`, first reason wins
+func (referee *Referee) terminate(reason error) {
+	referee.terminateOnce.Do(func() {
+		referee.reason = reason
+		close(referee.terminated)
+	})
 }
 
+func (referee *Referee) Info() GeneratorInfo {
+	return GeneratorInfo{
+		referee.ID,
+		referee.URI,
+		referee.caller.ID,
+		referee.executor.ID,
+		uint64(time.Since(referee.createdAt).Seconds()),
+		referee.rounds.Load(),
+	}
+}
+
+// executor must be waiting for next event, otherwise stop event is lost
 func (referee *Referee) stop() {
+	if departed(referee.executorDeparture) {
+		return
+	}
+
 	event := wamp.NewStopEvent(referee.ID)
 	features := event.Features()
 	features.VisitedRouters = append(features.VisitedRouters, referee.routerID)
@@ -30,6 +87,46 @@ func (referee *Referee) stop() {
 	}
 }
 
+// executor listens for stop event only while waiting for next event,
+// so stop is sent once it yields
+func (referee *Referee) stopAfter(yieldEventPromise wampShared.Promise[wamp.ReplyEvent]) {
+	select {
+	case response, done := <-yieldEventPromise:
+		if done && response.Kind() == wamp.MK_YIELD {
+			referee.stop()
+		}
+	case <-referee.executorDeparture:
+	}
+}
+
+// replies to pending next event of caller
+func (referee *Referee) reject(nextEvent wamp.NextEvent, reason error) {
+	errorEvent := wamp.NewErrorEvent(nextEvent, reason)
+	ok := referee.caller.Send(errorEvent, wamp.DEFAULT_RESEND_COUNT)
+	if !ok {
+		referee.logger.Error("error event dispatch error")
+	}
+}
+
+// idle caller waits for nothing, so reason is reported in reply to its next request
+func (referee *Referee) expire(
+	reason error,
+	nextEventPromise wampShared.Promise[wamp.NextEvent],
+	cancelNextEventPromise wampShared.CancelPromise,
+) {
+	referee.logger.Debug("generator expired", "reason", reason)
+	select {
+	case nextEvent := <-nextEventPromise:
+		referee.reject(nextEvent, reason)
+	case <-referee.stopEventPromise:
+		cancelNextEventPromise()
+	case <-referee.callerDeparture:
+		cancelNextEventPromise()
+	case <-time.After(time.Duration(wamp.DEFAULT_TIMEOUT) * time.Second):
+		cancelNextEventPromise()
+	}
+}
+
 func (referee *Referee) yield(nextEvent wamp.NextEvent) {
 	nextFeatures := nextEvent.Features()
 	timeout := time.Duration(nextFeatures.Timeout) * time.Second
@@ -45,11 +142,22 @@ func (referee *Referee) yield(nextEvent wamp.NextEvent) {
 				response = wamp.NewErrorEvent(nextEvent, wamp.ErrorTimedOut)
 			}
 
+			referee.rounds.Add(1)
 			referee.round(response)
 		case <-referee.stopEventPromise:
 			referee.logger.Debug("generator stop event received")
+			referee.stopAfter(yieldEventPromise)
+		case <-referee.terminated:
+			referee.logger.Debug("generator terminated", "reason", referee.reason)
+			referee.reject(nextEvent, referee.reason)
+			referee.stopAfter(yieldEventPromise)
+		case <-referee.callerDeparture:
+			referee.logger.Debug("caller left")
+			referee.stopAfter(yieldEventPromise)
+		case <-referee.executorDeparture:
+			referee.logger.Debug("executor left")
 			cancelYieldEventPromise()
-			referee.stop()
+			referee.reject(nextEvent, ErrorExecutorGone)
 		}
 	} else {
 		referee.logger.Error("next event dispatch error")
@@ -72,6 +180,19 @@ func (referee *Referee) next(yieldEvent wamp.YieldEvent) {
 			referee.logger.Debug("generator stop event received")
 			cancelNextEventPromise()
 			referee.stop()
+		case <-after(referee.idleTimeout):
+			referee.stop()
+			referee.expire(ErrorGeneratorIdle, nextEventPromise, cancelNextEventPromise)
+		case <-referee.terminated:
+			referee.stop()
+			referee.expire(referee.reason, nextEventPromise, cancelNextEventPromise)
+		case <-referee.callerDeparture:
+			referee.logger.Debug("caller left")
+			cancelNextEventPromise()
+			referee.stop()
+		case <-referee.executorDeparture:
+			referee.logger.Debug("executor left")
+			referee.expire(ErrorExecutorGone, nextEventPromise, cancelNextEventPromise)
 		}
 	} else {
 		referee.logger.Error("yield event dispatch error")
@@ -93,29 +214,68 @@ func (referee *Referee) round(response wamp.ReplyEvent) {
 	}
 }
 
-func loopGenerator(
-	routerID string,
+func (dealer *Dealer) loopGenerator(
 	caller *wamp.Peer,
 	executor *wamp.Peer,
 	callEvent wamp.CallEvent,
 	yieldEvent wamp.YieldEvent,
-	__logger *slog.Logger,
+	options *routerShared.RegisterOptions,
 ) {
 	callFeatures := callEvent.Features()
 	generator, _ := wamp.ReadPayload[wamp.NewGeneratorPayload](yieldEvent)
-	lifetime := time.Duration(wamp.DEFAULT_GENERATOR_LIFETIME) * time.Second
-	stopEventPromise, cancelStopEventPromise := executor.PendingCancelEvents.New(generator.ID, lifetime)
-	logger := __logger.With(
+	// caller sends stop event
+	stopEventPromise, cancelStopEventPromise := caller.PendingCancelEvents.New(generator.ID, 0)
+	lifetime := options.GeneratorLifetime()
+	idleTimeout := options.GeneratorIdleTimeout()
+	logger := dealer.logger.With(
 		"name", "Referee",
 		"GeneratorID", generator.ID,
 		"URI", callFeatures.URI,
 		"YieldID", yieldEvent.ID(),
 		"Lifetime", lifetime,
+		"IdleTimeout", idleTimeout,
 		"CallerID", caller.ID,
 		"ExecutorID", executor.ID,
 	)
-	referee := Referee{generator.ID, routerID, caller, executor, logger, stopEventPromise}
+	referee := &Referee{
+		ID:                generator.ID,
+		URI:               callFeatures.URI,
+		routerID:          dealer.routerID,
+		caller:            caller,
+		executor:          executor,
+		logger:            logger,
+		stopEventPromise:  stopEventPromise,
+		idleTimeout:       idleTimeout,
+		callerDeparture:   dealer.departure(caller.ID),
+		executorDeparture: dealer.departure(executor.ID),
+		createdAt:         time.Now(),
+		terminated:        make(chan struct{}),
+	}
+	expiration := time.AfterFunc(lifetime, func() { referee.terminate(ErrorGeneratorExpired) })
+	dealer.generators.Set(referee.ID, referee)
+
 	referee.next(yieldEvent)
+
+	dealer.generators.Remove(referee.ID)
+	expiration.Stop()
 	cancelStopEventPromise()
 	logger.Debug("destroy generator")
 }
+
+func (dealer *Dealer) generatorList() []GeneratorInfo {
+	result := []GeneratorInfo{}
+	for _, referee := range dealer.generators.Items() {
+		result = append(result, referee.Info())
+	}
+	return result
+}
+
+// stops executor and replies to caller with ErrorGeneratorStopped
+func (dealer *Dealer) stopGenerator(generatorID string) error {
+	referee, found := dealer.generators.Get(generatorID)
+	if !found {
+		return ErrorGeneratorNotFound
+	}
+	referee.terminate(ErrorGeneratorStopped)
+	return nil
+}
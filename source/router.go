@@ -2,6 +2,7 @@ package router
 
 import (
 	"log/slog"
+	"time"
 
 	wamp "github.com/wamp3hub/wamp3go"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
@@ -15,23 +16,33 @@ type Server interface {
 }
 
 type Router struct {
-	ID        string
-	metaPeer  *wamp.Peer
-	Session   *wamp.Session
-	KeyRing   *routerShared.KeyRing
-	Storage   routerShared.Storage
-	Broker    *Broker
-	Dealer    *Dealer
-	Newcomers *wampShared.Observable[*wamp.Peer]
-	logger    *slog.Logger
+	ID                    string
+	metaPeer              *wamp.Peer
+	Session               *wamp.Session
+	KeyRing               *routerShared.KeyRing
+	Authorizer            *routerShared.Authorizer
+	Storage               routerShared.Storage
+	Broker                *Broker
+	Dealer                *Dealer
+	Newcomers             *wampShared.Observable[*wamp.Peer]
+	restoredRegistrations RegistrationList
+	restoredSubscriptions SubscriptionList
+	logger                *slog.Logger
 }
 
 func NewRouter(
 	ID string,
 	storage routerShared.Storage,
 	keyRing *routerShared.KeyRing,
+	authorizer *routerShared.Authorizer,
+	callWorkersCount int,
 	logger *slog.Logger,
 ) *Router {
+	if authorizer == nil {
+		// static rules are disabled, hook may still be registered
+		authorizer, _ = routerShared.NewAuthorizer(nil, logger)
+	}
+
 	lTransport, rTransport := wampTransports.NewDuplexLocalTransport(128)
 	lPeer := wamp.SpawnPeer(ID, lTransport, logger)
 	rPeer := wamp.SpawnPeer(ID, rTransport, logger)
@@ -41,10 +52,13 @@ func NewRouter(
 		lPeer,
 		session,
 		keyRing,
+		authorizer,
 		storage,
-		NewBroker(ID, storage, logger),
-		NewDealer(ID, storage, logger),
+		NewBroker(ID, storage, authorizer, logger),
+		NewDealer(ID, storage, authorizer, callWorkersCount, logger),
 		wampShared.NewObservable[*wamp.Peer](),
+		RegistrationList{},
+		SubscriptionList{},
 		logger.With("name", "Router"),
 	}
 
@@ -56,6 +70,7 @@ func NewRouter(
 				func() {
 					router.unregister(peer.ID, "")
 					router.unsubscribe(peer.ID, "")
+					router.Authorizer.Forget(peer.ID)
 					router.logger.Info("dettach peer", "ID", peer.ID)
 				},
 			)
@@ -65,18 +80,108 @@ func NewRouter(
 		},
 	)
 
+	router.Authorizer.UseHook(router.callAuthorizationHook, routerShared.DEFAULT_AUTHORIZATION_TTL)
+
+	router.restore()
 	router.intialize()
 	return &router
 }
 
+// rebuilds registrations and subscriptions of previous run from storage
+func (router *Router) restore() {
+	registrationList, e := router.Dealer.registrations.Load()
+	if e != nil {
+		router.logger.Error("during restore registrations", "error", e)
+	}
+	for _, registration := range registrationList {
+		// registrations of router are mounted again during initialization
+		if registration.AuthorID == router.ID {
+			_, e := router.Dealer.registrations.DeleteByAuthor(registration.AuthorID, registration.ID)
+			if e != nil {
+				router.logger.Error("during delete router registration", "error", e, "ID", registration.ID)
+			}
+			continue
+		}
+		router.restoredRegistrations = append(router.restoredRegistrations, registration)
+	}
+
+	subscriptionList, e := router.Broker.subscriptions.Load()
+	if e != nil {
+		router.logger.Error("during restore subscriptions", "error", e)
+	}
+	router.restoredSubscriptions = subscriptionList
+
+	router.logger.Info(
+		"restore complete",
+		"registrationsCount", len(router.restoredRegistrations),
+		"subscriptionsCount", len(router.restoredSubscriptions),
+	)
+}
+
+// returns true if author has added another resource with the same URI since restore
+func superseded[T any](
+	resource *wamp.Resource[T],
+	resourceList routerShared.ResourceList[T],
+) bool {
+	for _, other := range resourceList {
+		if other.ID != resource.ID && other.URI == resource.URI {
+			return true
+		}
+	}
+	return false
+}
+
+func (router *Router) reconcile() {
+	for _, registration := range router.restoredRegistrations {
+		joined := router.Dealer.peers.Has(registration.AuthorID)
+		if !joined {
+			router.logger.Info("stale registration", "ID", registration.ID, "AuthorID", registration.AuthorID)
+			router.unregister(registration.AuthorID, registration.ID)
+		} else if superseded(registration, router.Dealer.registrations.GetByAuthor(registration.AuthorID)) {
+			router.logger.Info("superseded registration", "ID", registration.ID, "AuthorID", registration.AuthorID)
+			router.unregister(registration.AuthorID, registration.ID)
+		}
+	}
+	router.restoredRegistrations = RegistrationList{}
+
+	for _, subscription := range router.restoredSubscriptions {
+		joined := router.Broker.peers.Has(subscription.AuthorID)
+		if !joined {
+			router.logger.Info("stale subscription", "ID", subscription.ID, "AuthorID", subscription.AuthorID)
+			router.unsubscribe(subscription.AuthorID, subscription.ID)
+		} else if superseded(subscription, router.Broker.subscriptions.GetByAuthor(subscription.AuthorID)) {
+			router.logger.Info("superseded subscription", "ID", subscription.ID, "AuthorID", subscription.AuthorID)
+			router.unsubscribe(subscription.AuthorID, subscription.ID)
+		}
+	}
+	router.restoredSubscriptions = SubscriptionList{}
+}
+
+// Removes restored resources of peers which did not rejoin during grace period
+// and restored resources which were added again by rejoined peers.
+func (router *Router) Reconcile(gracePeriod time.Duration) {
+	router.logger.Info("reconcile scheduled", "gracePeriod", gracePeriod)
+	time.AfterFunc(gracePeriod, router.reconcile)
+}
+
 func (router *Router) Serve() {
 	router.logger.Info("up...")
 	router.Broker.Serve(router.Newcomers)
 	router.Dealer.Serve(router.Newcomers)
+	// meta peer is attached through local transport, so it can not be impersonated
+	router.Authorizer.Trust(router.metaPeer.ID)
 	router.Newcomers.Next(router.metaPeer)
 }
 
 func (router *Router) Shutdown() {
 	router.logger.Info("shutting down...")
 	router.Newcomers.Complete()
+
+	flusher, ok := router.Storage.(routerShared.StorageFlusher)
+	if ok {
+		e := flusher.Flush()
+		if e != nil {
+			router.logger.Error("during flush storage", "error", e)
+		}
+	}
 }
@@ -1,8 +1,12 @@
 package router_test
 
 import (
+	"errors"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,32 +19,60 @@ import (
 )
 
 func runRouter() *wampShared.Observable[*wamp.Peer] {
-	routerID := wampShared.NewID()
-	storagePath := "/tmp/wamp3rd-" + routerID + ".db"
-	storage, _ := routerStorages.NewBoltDBStorage(storagePath)
+	return runRouterWithCallWorkers(router.DEFAULT_CALL_WORKERS_COUNT)
+}
+
+func runRouterWithCallWorkers(callWorkersCount int) *wampShared.Observable[*wamp.Peer] {
+	__router := spawnRouter(wampShared.NewID(), callWorkersCount, nil)
+	return __router.Newcomers
+}
+
+func spawnRouter(
+	routerID string,
+	callWorkersCount int,
+	authorizer *routerShared.Authorizer,
+) *router.Router {
+	storage := routerStorages.NewMemoryStorage()
 	keyRing := routerShared.GenerateKeyRing()
 	__router := router.NewRouter(
 		routerID,
 		storage,
 		keyRing,
+		authorizer,
+		callWorkersCount,
 		slog.Default(),
 	)
 	__router.Serve()
-	return __router.Newcomers
+	return __router
 }
 
 func joinSession(
 	newcomers *wampShared.Observable[*wamp.Peer],
 ) *wamp.Session {
+	session, _ := joinSessionPeer(newcomers)
+	return session
+}
+
+// returns session and its own peer, which allows to handle events manually
+func joinSessionPeer(
+	newcomers *wampShared.Observable[*wamp.Peer],
+) (*wamp.Session, *wamp.Peer) {
+	return joinSessionAs(newcomers, wampShared.NewID())
+}
+
+// joins with known id, like peer which rejoins after restart of router
+func joinSessionAs(
+	newcomers *wampShared.Observable[*wamp.Peer],
+	alphaID string,
+) (*wamp.Session, *wamp.Peer) {
 	logger := slog.Default()
-	alphaID := wampShared.NewID()
 	lTransport, rTransport := wampTransports.NewDuplexLocalTransport(128)
 	lPeer := wamp.SpawnPeer(alphaID, lTransport, logger)
 	rPeer := wamp.SpawnPeer(alphaID, rTransport, logger)
 	session := wamp.NewSession(rPeer, logger)
 	newcomers.Next(lPeer)
 	time.Sleep(time.Second)
-	return session
+	return session, rPeer
 }
 
 func TestSubscribePublish(t *testing.T) {
@@ -89,6 +121,107 @@ func TestSubscribePublish(t *testing.T) {
 			t.Fatalf("unsubscribe error %s", e)
 		}
 	})
+
+	t.Run("Case: Multi-level wildcard", func(t *testing.T) {
+		alphaSession := joinSession(nextNewcomer)
+		betaSession := joinSession(nextNewcomer)
+
+		wg := new(sync.WaitGroup)
+
+		subscription, e := wamp.Subscribe(
+			alphaSession,
+			"net.**",
+			&wamp.SubscribeOptions{},
+			func(message string, publishEvent wamp.PublishEvent) {
+				t.Logf("new message %s", message)
+				wg.Done()
+			},
+		)
+		if e == nil {
+			t.Logf("subscribe success ID=%s", subscription.ID)
+		} else {
+			t.Fatalf("subscribe error %s", e)
+		}
+
+		for _, uri := range []string{"net.example", "net.example.deep.topic"} {
+			wg.Add(1)
+
+			e = wamp.Publish(
+				betaSession,
+				&wamp.PublishFeatures{URI: uri},
+				"Hello, I'm session beta!",
+			)
+			if e == nil {
+				t.Logf("publish success")
+			} else {
+				t.Fatalf("publish error %s", e)
+			}
+
+			wg.Wait()
+		}
+
+		e = wamp.Unsubscribe(alphaSession, subscription.ID)
+		if e == nil {
+			t.Logf("unsubscribe success")
+		} else {
+			t.Fatalf("unsubscribe error %s", e)
+		}
+	})
+}
+
+func TestSubscribeMatchPolicy(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	alphaSession := joinSession(nextNewcomer)
+	betaSession := joinSession(nextNewcomer)
+
+	wg := new(sync.WaitGroup)
+
+	pendingResponse := wamp.Call[*wamp.Subscription](
+		alphaSession,
+		&wamp.CallFeatures{URI: "wamp.router.subscribe"},
+		wamp.NewResourcePayload[routerShared.SubscribeOptions]{
+			URI:     "com.acme.telemetry",
+			Options: &routerShared.SubscribeOptions{Match: routerShared.MATCH_PREFIX},
+		},
+	)
+	_, subscription, e := pendingResponse.Await()
+	if e == nil {
+		t.Logf("subscribe success ID=%s", subscription.ID)
+	} else {
+		t.Fatalf("subscribe error %s", e)
+	}
+	alphaSession.Subscriptions[subscription.ID] = wamp.NewPublishEventEndpoint(
+		func(message string, publishEvent wamp.PublishEvent) {
+			t.Logf("new message %s", message)
+			wg.Done()
+		},
+		slog.Default(),
+	)
+
+	for _, uri := range []string{"com.acme.telemetry", "com.acme.telemetry.cpu.load"} {
+		wg.Add(1)
+
+		e = wamp.Publish(
+			betaSession,
+			&wamp.PublishFeatures{URI: uri},
+			"Hello, I'm session beta!",
+		)
+		if e == nil {
+			t.Logf("publish success")
+		} else {
+			t.Fatalf("publish error %s", e)
+		}
+
+		wg.Wait()
+	}
+
+	e = wamp.Unsubscribe(alphaSession, subscription.ID)
+	if e == nil {
+		t.Logf("unsubscribe success")
+	} else {
+		t.Fatalf("unsubscribe error %s", e)
+	}
 }
 
 func TestRPC(t *testing.T) {
@@ -178,8 +311,82 @@ func TestRPC(t *testing.T) {
 	})
 }
 
+func TestRestore(t *testing.T) {
+	keyRing := routerShared.GenerateKeyRing()
+	spawn := func(routerID string, storage routerShared.Storage) *router.Router {
+		__router := router.NewRouter(routerID, storage, keyRing, nil, router.DEFAULT_CALL_WORKERS_COUNT, slog.Default())
+		__router.Serve()
+		return __router
+	}
+
+	t.Run("Case: Router Registrations", func(t *testing.T) {
+		routerID := wampShared.NewID()
+		storage := routerStorages.NewMemoryStorage()
+		// previous run leaves registrations of router in storage
+		spawn(routerID, storage)
+
+		__router := spawn(routerID, storage)
+		session := joinSession(__router.Newcomers)
+
+		generator, e := wamp.CallGenerator[router.RegistrationList](
+			session,
+			&wamp.CallFeatures{URI: "wamp.router.registration.list"},
+			struct{}{},
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		count := map[string]int{}
+		for generator.Active() {
+			_, registrationList, e := generator.Next(wamp.DEFAULT_TIMEOUT)
+			if e != nil {
+				break
+			}
+			for _, registration := range registrationList {
+				count[registration.URI]++
+			}
+		}
+		for _, uri := range []string{"wamp.router.register", "wamp.ticket.generate"} {
+			if count[uri] != 1 {
+				t.Fatalf("expected single registration of %s, but got %d", uri, count[uri])
+			}
+		}
+	})
+
+	t.Run("Case: Single Registration", func(t *testing.T) {
+		routerID := wampShared.NewID()
+		storage := routerStorages.NewMemoryStorage()
+		ownerID := wampShared.NewID()
+		options := &routerShared.RegisterOptions{Invoke: routerShared.INVOKE_SINGLE}
+		echo := func(payload string, callEvent wamp.CallEvent) (string, error) {
+			return payload, nil
+		}
+
+		ownerSession, _ := joinSessionAs(spawn(routerID, storage).Newcomers, ownerID)
+		_, e := registerWithOptions(ownerSession, "net.example.single", options, echo)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		// owner replaces its own restored registration
+		ownerSession, _ = joinSessionAs(spawn(routerID, storage).Newcomers, ownerID)
+		_, e = registerWithOptions(ownerSession, "net.example.single", options, echo)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		// restored registration of owner which has not rejoined does not hold procedure
+		otherSession := joinSession(spawn(routerID, storage).Newcomers)
+		_, e = registerWithOptions(otherSession, "net.example.single", options, echo)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+	})
+}
+
 func TestGenerator(t *testing.T) {
-	nextNewcomer := runRouter()
+	__router := spawnRouter(wampShared.NewID(), router.DEFAULT_CALL_WORKERS_COUNT, nil)
+	nextNewcomer := __router.Newcomers
 
 	alphaSession := joinSession(nextNewcomer)
 
@@ -257,4 +464,1450 @@ func TestGenerator(t *testing.T) {
 			t.Fatalf("stop generator error %s", e)
 		}
 	})
+
+	// returns generator which closes `stopped` when executor destroys it
+	countdown := func(delay time.Duration, stopped chan struct{}) wamp.ProcedureToCall[int, int] {
+		return func(n int, callEvent wamp.CallEvent) (int, error) {
+			defer close(stopped)
+			source := wamp.Event(callEvent)
+			for i := n; i > -1; i-- {
+				time.Sleep(delay)
+				source = wamp.Yield(source, i)
+			}
+			return -1, wamp.GeneratorExit(source)
+		}
+	}
+
+	awaitStopped := func(t *testing.T, stopped chan struct{}) {
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("executor generator is still running")
+		}
+	}
+
+	t.Run("Case: Stop Reaches Executor", func(t *testing.T) {
+		stopped := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.stop",
+			&routerShared.RegisterOptions{},
+			countdown(0, stopped),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		betaSession := joinSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			betaSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.stop"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		e = generator.Stop()
+		if e != nil {
+			t.Fatalf("stop generator error %s", e)
+		}
+		awaitStopped(t, stopped)
+	})
+
+	t.Run("Case: Idle Timeout", func(t *testing.T) {
+		stopped := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.idle",
+			&routerShared.RegisterOptions{IdleTimeout: 1},
+			countdown(0, stopped),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		betaSession := joinSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			betaSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.idle"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+
+		time.Sleep(1500 * time.Millisecond)
+		awaitStopped(t, stopped)
+
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e == nil || e.Error() != router.ErrorGeneratorIdle.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorGeneratorIdle, e)
+		}
+	})
+
+	t.Run("Case: Lifetime", func(t *testing.T) {
+		stopped := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.lifetime",
+			&routerShared.RegisterOptions{Lifetime: 1},
+			countdown(200*time.Millisecond, stopped),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		betaSession := joinSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			betaSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.lifetime"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+
+		for generator.Active() {
+			_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		}
+		if e == nil || e.Error() != router.ErrorGeneratorExpired.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorGeneratorExpired, e)
+		}
+		awaitStopped(t, stopped)
+	})
+
+	t.Run("Case: Caller Leaves", func(t *testing.T) {
+		stopped := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.callerleaves",
+			&routerShared.RegisterOptions{},
+			countdown(0, stopped),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		callerSession := joinClosableSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			callerSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.callerleaves"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+
+		wamp.Leave(callerSession, "crash")
+		awaitStopped(t, stopped)
+	})
+
+	t.Run("Case: Executor Leaves", func(t *testing.T) {
+		executorSession := joinClosableSession(nextNewcomer)
+		_, e := registerWithOptions(
+			executorSession,
+			"net.example.countdown.executorleaves",
+			&routerShared.RegisterOptions{},
+			countdown(0, make(chan struct{})),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		betaSession := joinSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			betaSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.executorleaves"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+
+		wamp.Leave(executorSession, "crash")
+		time.Sleep(100 * time.Millisecond)
+
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e == nil || e.Error() != router.ErrorExecutorGone.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorExecutorGone, e)
+		}
+	})
+
+	t.Run("Case: List And Stop", func(t *testing.T) {
+		stopped := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.runaway",
+			&routerShared.RegisterOptions{},
+			countdown(0, stopped),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		betaSession := joinSession(nextNewcomer)
+		generator, e := wamp.CallGenerator[int](
+			betaSession,
+			&wamp.CallFeatures{URI: "net.example.countdown.runaway"},
+			100,
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		for i := 0; i < 2; i++ {
+			_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+			if e != nil {
+				t.Fatalf("generator error %s", e)
+			}
+		}
+
+		// generators of every peer are exposed to operator only
+		_, e = wamp.CallGenerator[router.GeneratorInfo](
+			betaSession,
+			&wamp.CallFeatures{URI: "wamp.router.generator.list"},
+			struct{}{},
+		)
+		if e == nil || e.Error() != routerShared.ErrorNotAuthorized.Error() {
+			t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+		}
+
+		operatorSession := joinSession(nextNewcomer)
+		__router.Authorizer.Trust(operatorSession.ID())
+
+		list, e := wamp.CallGenerator[router.GeneratorInfo](
+			operatorSession,
+			&wamp.CallFeatures{URI: "wamp.router.generator.list"},
+			struct{}{},
+		)
+		if e != nil {
+			t.Fatalf("generator list error %s", e)
+		}
+		var runaway *router.GeneratorInfo
+		for list.Active() {
+			_, info, e := list.Next(wamp.DEFAULT_TIMEOUT)
+			if e != nil {
+				break
+			}
+			if info.URI == "net.example.countdown.runaway" {
+				runaway = &info
+			}
+		}
+		if runaway == nil {
+			t.Fatal("generator list expected runaway generator")
+		}
+		if runaway.CallerID != betaSession.ID() ||
+			runaway.ExecutorID != alphaSession.ID() ||
+			runaway.Rounds != 2 {
+			t.Fatalf("unexpected generator info %+v", runaway)
+		}
+
+		_, _, e = wamp.Call[struct{}](
+			betaSession,
+			&wamp.CallFeatures{URI: "wamp.router.generator.stop"},
+			runaway.ID,
+		).Await()
+		if e == nil || e.Error() != routerShared.ErrorNotAuthorized.Error() {
+			t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+		}
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+
+		_, _, e = wamp.Call[struct{}](
+			operatorSession,
+			&wamp.CallFeatures{URI: "wamp.router.generator.stop"},
+			runaway.ID,
+		).Await()
+		if e != nil {
+			t.Fatalf("generator stop error %s", e)
+		}
+		awaitStopped(t, stopped)
+
+		_, _, e = generator.Next(wamp.DEFAULT_TIMEOUT)
+		if e == nil || e.Error() != router.ErrorGeneratorStopped.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorGeneratorStopped, e)
+		}
+
+		_, _, e = wamp.Call[struct{}](
+			operatorSession,
+			&wamp.CallFeatures{URI: "wamp.router.generator.stop"},
+			runaway.ID,
+		).Await()
+		if e == nil || e.Error() != router.ErrorGeneratorNotFound.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorGeneratorNotFound, e)
+		}
+	})
+
+	t.Run("Case: Invalid Timeout", func(t *testing.T) {
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.countdown.invalid",
+			&routerShared.RegisterOptions{IdleTimeout: -1},
+			countdown(0, make(chan struct{})),
+		)
+		if e == nil || e.Error() != router.ErrorInvalidGeneratorTimeout.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorInvalidGeneratorTimeout, e)
+		}
+	})
+}
+
+// local transport which may be closed safely during writes
+type closableTransport struct {
+	tq     chan wamp.Event
+	rq     chan wamp.Event
+	closed chan struct{}
+	once   *sync.Once
+}
+
+func newDuplexClosableTransport(qSize int) (*closableTransport, *closableTransport) {
+	left := make(chan wamp.Event, qSize)
+	right := make(chan wamp.Event, qSize)
+	closed := make(chan struct{})
+	once := new(sync.Once)
+	return &closableTransport{left, right, closed, once}, &closableTransport{right, left, closed, once}
+}
+
+func (transport *closableTransport) Close() error {
+	transport.once.Do(func() { close(transport.closed) })
+	return nil
+}
+
+func (transport *closableTransport) Write(event wamp.Event) error {
+	// select picks ready case randomly, so closing must be checked first
+	select {
+	case <-transport.closed:
+		return wamp.ErrorConnectionClosed
+	default:
+	}
+
+	select {
+	case <-transport.closed:
+		return wamp.ErrorConnectionClosed
+	case transport.tq <- event:
+		return nil
+	}
+}
+
+func (transport *closableTransport) Read() (wamp.Event, error) {
+	select {
+	case <-transport.closed:
+		return nil, wamp.ErrorConnectionClosed
+	default:
+	}
+
+	select {
+	case <-transport.closed:
+		return nil, wamp.ErrorConnectionClosed
+	case event := <-transport.rq:
+		return event, nil
+	}
+}
+
+func joinClosableSession(
+	newcomers *wampShared.Observable[*wamp.Peer],
+) *wamp.Session {
+	logger := slog.Default()
+	alphaID := wampShared.NewID()
+	lTransport, rTransport := newDuplexClosableTransport(128)
+	lPeer := wamp.SpawnPeer(alphaID, lTransport, logger)
+	rPeer := wamp.SpawnPeer(alphaID, rTransport, logger)
+	session := wamp.NewSession(rPeer, logger)
+	newcomers.Next(lPeer)
+	return session
+}
+
+func TestConcurrentPeers(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	executorSession := joinSession(nextNewcomer)
+	_, e := wamp.Register(
+		executorSession,
+		"net.example.echo",
+		&wamp.RegisterOptions{},
+		func(message string, callEvent wamp.CallEvent) (string, error) {
+			return message, nil
+		},
+	)
+	if e != nil {
+		t.Fatalf("register error %s", e)
+	}
+
+	subscriberSession := joinSession(nextNewcomer)
+	_, e = wamp.Subscribe(
+		subscriberSession,
+		"net.example.topic",
+		&wamp.SubscribeOptions{},
+		func(message string, publishEvent wamp.PublishEvent) {},
+	)
+	if e != nil {
+		t.Fatalf("subscribe error %s", e)
+	}
+
+	n := 16
+	wg := new(sync.WaitGroup)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			session := joinClosableSession(nextNewcomer)
+			defer wamp.Leave(session, "done")
+
+			// nobody publishes or calls private resources,
+			// so they only modify URIM concurrently with matching
+			privateURI := "net.example.private." + strings.ToLower(session.ID())
+			_, e := wamp.Subscribe(
+				session,
+				privateURI,
+				&wamp.SubscribeOptions{},
+				func(message string, publishEvent wamp.PublishEvent) {},
+			)
+			if e != nil {
+				t.Errorf("subscribe error %s", e)
+				return
+			}
+			_, e = wamp.Register(
+				session,
+				privateURI,
+				&wamp.RegisterOptions{},
+				func(message string, callEvent wamp.CallEvent) (string, error) {
+					return message, nil
+				},
+			)
+			if e != nil {
+				t.Errorf("register error %s", e)
+				return
+			}
+
+			for j := 0; j < 4; j++ {
+				e = wamp.Publish(
+					session,
+					&wamp.PublishFeatures{URI: "net.example.topic"},
+					"Hello!",
+				)
+				if e != nil {
+					t.Errorf("publish error %s", e)
+				}
+
+				pendingResponse := wamp.Call[string](
+					session,
+					&wamp.CallFeatures{URI: "net.example.echo"},
+					"Hello!",
+				)
+				_, result, e := pendingResponse.Await()
+				if e != nil || result != "Hello!" {
+					t.Errorf("RPC expected Hello!, but got %v (error=%v)", result, e)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// stable peers must survive
+	pendingResponse := wamp.Call[string](
+		subscriberSession,
+		&wamp.CallFeatures{URI: "net.example.echo"},
+		"Hello!",
+	)
+	_, result, e := pendingResponse.Await()
+	if e != nil || result != "Hello!" {
+		t.Fatalf("RPC expected Hello!, but got %v (error=%v)", result, e)
+	}
+}
+
+// registers procedure with router specific options
+func registerWithOptions[I, O any](
+	session *wamp.Session,
+	uri string,
+	options *routerShared.RegisterOptions,
+	procedure wamp.ProcedureToCall[I, O],
+) (*wamp.Registration, error) {
+	pendingResponse := wamp.Call[*wamp.Registration](
+		session,
+		&wamp.CallFeatures{URI: "wamp.router.register"},
+		wamp.NewResourcePayload[routerShared.RegisterOptions]{URI: uri, Options: options},
+	)
+	_, registration, e := pendingResponse.Await()
+	if e == nil {
+		session.Registrations[registration.ID] = wamp.NewCallEventEndpoint(procedure, slog.Default())
+	}
+	return registration, e
+}
+
+func TestInvocationPolicy(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	alphaSession := joinSession(nextNewcomer)
+	betaSession := joinSession(nextNewcomer)
+	callerSession := joinSession(nextNewcomer)
+
+	whoami := func(name string) wamp.ProcedureToCall[string, string] {
+		return func(payload string, callEvent wamp.CallEvent) (string, error) {
+			return name, nil
+		}
+	}
+
+	call := func(uri string) string {
+		pendingResponse := wamp.Call[string](
+			callerSession,
+			&wamp.CallFeatures{URI: uri},
+			"",
+		)
+		_, result, e := pendingResponse.Await()
+		if e != nil {
+			t.Fatalf("call error %s", e)
+		}
+		return result
+	}
+
+	t.Run("Case: Single", func(t *testing.T) {
+		options := &routerShared.RegisterOptions{Invoke: routerShared.INVOKE_SINGLE}
+		_, e := registerWithOptions(alphaSession, "net.example.single", options, whoami("alpha"))
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(betaSession, "net.example.single", options, whoami("beta"))
+		if e == nil || e.Error() != router.ErrorProcedureAlreadyExists.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorProcedureAlreadyExists, e)
+		}
+	})
+
+	t.Run("Case: Conflict", func(t *testing.T) {
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.conflict",
+			&routerShared.RegisterOptions{Invoke: routerShared.INVOKE_FIRST},
+			whoami("alpha"),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(
+			betaSession,
+			"net.example.conflict",
+			&routerShared.RegisterOptions{Invoke: routerShared.INVOKE_LAST},
+			whoami("beta"),
+		)
+		if e == nil || e.Error() != router.ErrorInvocationPolicyConflict.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorInvocationPolicyConflict, e)
+		}
+	})
+
+	t.Run("Case: Invalid", func(t *testing.T) {
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.invalid",
+			&routerShared.RegisterOptions{Invoke: "unknown"},
+			whoami("alpha"),
+		)
+		if e == nil || e.Error() != router.ErrorInvalidInvocationPolicy.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorInvalidInvocationPolicy, e)
+		}
+	})
+
+	testCases := []struct {
+		policy   string
+		expected []string
+	}{
+		{routerShared.INVOKE_FIRST, []string{"alpha", "alpha", "alpha"}},
+		{routerShared.INVOKE_LAST, []string{"beta", "beta", "beta"}},
+		{routerShared.INVOKE_ROUNDROBIN, []string{"alpha", "beta", "alpha"}},
+	}
+	for _, testCase := range testCases {
+		t.Run("Case: "+testCase.policy, func(t *testing.T) {
+			uri := "net.example." + testCase.policy
+			options := &routerShared.RegisterOptions{Invoke: testCase.policy}
+			for _, name := range []string{"alpha", "beta"} {
+				session := alphaSession
+				if name == "beta" {
+					session = betaSession
+				}
+				_, e := registerWithOptions(session, uri, options, whoami(name))
+				if e != nil {
+					t.Fatalf("register error %s", e)
+				}
+			}
+
+			for _, expected := range testCase.expected {
+				result := call(uri)
+				if result != expected {
+					t.Fatalf("call expected %s, but got %s", expected, result)
+				}
+			}
+		})
+	}
+
+	t.Run("Case: Weighted", func(t *testing.T) {
+		uri := "net.example.weighted"
+		weights := map[string]int{"alpha": 3, "beta": 1}
+		for _, name := range []string{"alpha", "beta"} {
+			session := alphaSession
+			if name == "beta" {
+				session = betaSession
+			}
+			_, e := registerWithOptions(session, uri, &routerShared.RegisterOptions{Weight: weights[name]}, whoami(name))
+			if e != nil {
+				t.Fatalf("register error %s", e)
+			}
+		}
+
+		// smooth weighted round robin interleaves calls
+		for _, expected := range []string{"alpha", "alpha", "beta", "alpha", "alpha", "alpha", "beta", "alpha"} {
+			result := call(uri)
+			if result != expected {
+				t.Fatalf("call expected %s, but got %s", expected, result)
+			}
+		}
+
+		generator, e := wamp.CallGenerator[router.RegistrationList](
+			callerSession,
+			&wamp.CallFeatures{URI: "wamp.router.registration.list"},
+			struct{}{},
+		)
+		if e != nil {
+			t.Fatalf("generator error %s", e)
+		}
+		found := 0
+		for generator.Active() {
+			_, registrationList, e := generator.Next(wamp.DEFAULT_TIMEOUT)
+			if e != nil {
+				break
+			}
+			for _, registration := range registrationList {
+				if registration.URI != uri {
+					continue
+				}
+				found++
+				if registration.Options.Weight != 3 && registration.Options.Weight != 1 {
+					t.Fatalf("unexpected weight %d", registration.Options.Weight)
+				}
+			}
+		}
+		if found != 2 {
+			t.Fatalf("registration list expected 2 weighted registrations, but got %d", found)
+		}
+	})
+
+	t.Run("Case: Exact takes precedence", func(t *testing.T) {
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.precedence.*",
+			&routerShared.RegisterOptions{},
+			whoami("alpha"),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(
+			betaSession,
+			"net.example.precedence.exact",
+			&routerShared.RegisterOptions{Match: routerShared.MATCH_EXACT},
+			whoami("beta"),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		for i := 0; i < 2; i++ {
+			result := call("net.example.precedence.exact")
+			if result != "beta" {
+				t.Fatalf("call expected beta, but got %s", result)
+			}
+		}
+	})
+}
+
+// counts calls being handled at once
+type gauge struct {
+	mutex   sync.Mutex
+	current int
+	peak    int
+	total   int
+}
+
+// returns procedure which takes half a second and reports to gauge
+func slowProcedure(g *gauge) wamp.ProcedureToCall[string, string] {
+	return func(payload string, callEvent wamp.CallEvent) (string, error) {
+		g.mutex.Lock()
+		g.current++
+		g.total++
+		g.peak = max(g.peak, g.current)
+		g.mutex.Unlock()
+
+		time.Sleep(500 * time.Millisecond)
+
+		g.mutex.Lock()
+		g.current--
+		g.mutex.Unlock()
+		return payload, nil
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	alphaSession := joinSession(nextNewcomer)
+	betaSession := joinSession(nextNewcomer)
+
+	callConcurrently := func(uri string, n int) {
+		wg := new(sync.WaitGroup)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				callerSession := joinClosableSession(nextNewcomer)
+				defer wamp.Leave(callerSession, "done")
+				pendingResponse := wamp.Call[string](
+					callerSession,
+					&wamp.CallFeatures{URI: uri},
+					"Hello!",
+				)
+				_, result, e := pendingResponse.Await()
+				if e != nil || result != "Hello!" {
+					t.Errorf("RPC expected Hello!, but got %v (error=%v)", result, e)
+				}
+			}()
+			// gives router time to dispatch call
+			time.Sleep(50 * time.Millisecond)
+		}
+		wg.Wait()
+	}
+
+	t.Run("Case: Queue", func(t *testing.T) {
+		alphaGauge := new(gauge)
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.limited",
+			&routerShared.RegisterOptions{Concurrency: 1},
+			slowProcedure(alphaGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		callConcurrently("net.example.limited", 3)
+
+		if alphaGauge.peak != 1 || alphaGauge.total != 3 {
+			t.Fatalf("expected 3 calls one by one, but got %d calls with peak %d", alphaGauge.total, alphaGauge.peak)
+		}
+	})
+
+	t.Run("Case: Per executor", func(t *testing.T) {
+		narrowGauge := new(gauge)
+		wideGauge := new(gauge)
+		releaseNarrow := make(chan struct{})
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.narrow",
+			&routerShared.RegisterOptions{Concurrency: 3},
+			func(payload string, callEvent wamp.CallEvent) (string, error) {
+				narrowGauge.mutex.Lock()
+				narrowGauge.total++
+				narrowGauge.mutex.Unlock()
+				<-releaseNarrow
+				return payload, nil
+			},
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(
+			alphaSession,
+			"net.example.wide",
+			&routerShared.RegisterOptions{Concurrency: 3},
+			slowProcedure(wideGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			callConcurrently("net.example.narrow", 1)
+		}()
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			narrowGauge.mutex.Lock()
+			started := narrowGauge.total
+			narrowGauge.mutex.Unlock()
+			if started > 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("narrow call has not started")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// call in flight of one procedure counts against limit of the same executor
+		callConcurrently("net.example.wide", 3)
+		close(releaseNarrow)
+		<-done
+
+		if wideGauge.peak != 2 || wideGauge.total != 3 {
+			t.Fatalf("expected 3 calls with peak 2, but got %d calls with peak %d", wideGauge.total, wideGauge.peak)
+		}
+	})
+
+	t.Run("Case: Least loaded", func(t *testing.T) {
+		alphaGauge := new(gauge)
+		betaGauge := new(gauge)
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.loaded",
+			&routerShared.RegisterOptions{Invoke: routerShared.INVOKE_LEASTLOADED, Concurrency: 2},
+			slowProcedure(alphaGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(
+			betaSession,
+			"net.example.loaded",
+			&routerShared.RegisterOptions{Invoke: routerShared.INVOKE_LEASTLOADED, Concurrency: 4},
+			slowProcedure(betaGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		callConcurrently("net.example.loaded", 3)
+
+		if alphaGauge.total != 1 || betaGauge.total != 2 {
+			t.Fatalf("expected alpha=1 and beta=2, but got alpha=%d and beta=%d", alphaGauge.total, betaGauge.total)
+		}
+	})
+}
+
+func TestFailover(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	callerSession := joinSession(nextNewcomer)
+
+	call := func(uri string) (string, error) {
+		pendingResponse := wamp.Call[string](
+			callerSession,
+			&wamp.CallFeatures{URI: uri, Timeout: 1},
+			"",
+		)
+		_, result, e := pendingResponse.Await()
+		return result, e
+	}
+
+	greeting := func(payload string, callEvent wamp.CallEvent) (string, error) {
+		return "Hello!", nil
+	}
+
+	t.Run("Case: Timeout", func(t *testing.T) {
+		alphaSession := joinSession(nextNewcomer)
+		betaSession := joinSession(nextNewcomer)
+
+		options := &routerShared.RegisterOptions{Invoke: routerShared.INVOKE_FIRST, Failover: 1}
+		_, e := registerWithOptions(
+			alphaSession,
+			"net.example.slow",
+			options,
+			func(payload string, callEvent wamp.CallEvent) (string, error) {
+				time.Sleep(3 * time.Second)
+				return "Too late!", nil
+			},
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+		_, e = registerWithOptions(betaSession, "net.example.slow", options, greeting)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		result, e := call("net.example.slow")
+		if e != nil || result != "Hello!" {
+			t.Fatalf("RPC expected Hello!, but got %v (error=%v)", result, e)
+		}
+	})
+
+	t.Run("Case: Shared Deadline", func(t *testing.T) {
+		options := &routerShared.RegisterOptions{Invoke: routerShared.INVOKE_FIRST, Failover: 2}
+		slow := func(payload string, callEvent wamp.CallEvent) (string, error) {
+			time.Sleep(3 * time.Second)
+			return "Too late!", nil
+		}
+		for _, procedure := range []wamp.ProcedureToCall[string, string]{slow, slow, greeting} {
+			session := joinSession(nextNewcomer)
+			_, e := registerWithOptions(session, "net.example.slower", options, procedure)
+			if e != nil {
+				t.Fatalf("register error %s", e)
+			}
+		}
+
+		// caller waits no longer than twice timeout, so attempts must fit into timeout
+		result, e := call("net.example.slower")
+		if e != nil || result != "Hello!" {
+			t.Fatalf("RPC expected Hello!, but got %v (error=%v)", result, e)
+		}
+	})
+
+	t.Run("Case: Executor gone", func(t *testing.T) {
+		for _, failover := range []int{0, 1} {
+			alphaSession := joinClosableSession(nextNewcomer)
+			betaSession := joinSession(nextNewcomer)
+			time.Sleep(time.Second)
+
+			uri := "net.example.gone" + strconv.Itoa(failover)
+			options := &routerShared.RegisterOptions{Invoke: routerShared.INVOKE_FIRST, Failover: failover}
+			_, e := registerWithOptions(
+				alphaSession,
+				uri,
+				options,
+				func(payload string, callEvent wamp.CallEvent) (string, error) {
+					wamp.Leave(alphaSession, "crash")
+					return "Unreachable!", nil
+				},
+			)
+			if e != nil {
+				t.Fatalf("register error %s", e)
+			}
+			_, e = registerWithOptions(betaSession, uri, options, greeting)
+			if e != nil {
+				t.Fatalf("register error %s", e)
+			}
+
+			result, e := call(uri)
+			if failover > 0 {
+				if e != nil || result != "Hello!" {
+					t.Fatalf("RPC expected Hello!, but got %v (error=%v)", result, e)
+				}
+			} else if e == nil || e.Error() != router.ErrorExecutorGone.Error() {
+				t.Fatalf("expected %s, but got %v", router.ErrorExecutorGone, e)
+			}
+		}
+	})
+}
+
+func TestCallWorkers(t *testing.T) {
+	// every call of single caller is sent at once
+	callAtOnce := func(session *wamp.Session, uri string, n int) time.Duration {
+		startedAt := time.Now()
+		wg := new(sync.WaitGroup)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pendingResponse := wamp.Call[string](
+					session,
+					&wamp.CallFeatures{URI: uri},
+					"Hello!",
+				)
+				_, result, e := pendingResponse.Await()
+				if e != nil || result != "Hello!" {
+					t.Errorf("RPC expected Hello!, but got %v (error=%v)", result, e)
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Since(startedAt)
+	}
+
+	t.Run("Case: Capped", func(t *testing.T) {
+		nextNewcomer := runRouterWithCallWorkers(4)
+		executorSession := joinSession(nextNewcomer)
+		callerSession := joinSession(nextNewcomer)
+
+		executorGauge := new(gauge)
+		_, e := wamp.Register(
+			executorSession,
+			"net.example.slow",
+			&wamp.RegisterOptions{},
+			slowProcedure(executorGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		// calls are processed in parallel, but no more than call workers count at once
+		elapsed := callAtOnce(callerSession, "net.example.slow", 8)
+		if executorGauge.peak != 4 || executorGauge.total != 8 {
+			t.Fatalf("expected 8 calls with peak 4, but got %d calls with peak %d", executorGauge.total, executorGauge.peak)
+		}
+		// two rounds of half second each
+		if elapsed > 1500*time.Millisecond {
+			t.Fatalf("calls must be processed in parallel, took %s", elapsed)
+		}
+	})
+
+	t.Run("Case: Bounded", func(t *testing.T) {
+		nextNewcomer := runRouterWithCallWorkers(2)
+		executorSession := joinSession(nextNewcomer)
+		callerSession := joinSession(nextNewcomer)
+
+		executorGauge := new(gauge)
+		_, e := wamp.Register(
+			executorSession,
+			"net.example.slow",
+			&wamp.RegisterOptions{},
+			slowProcedure(executorGauge),
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		callAtOnce(callerSession, "net.example.slow", 6)
+		if executorGauge.peak != 2 || executorGauge.total != 6 {
+			t.Fatalf("expected 6 calls with peak 2, but got %d calls with peak %d", executorGauge.total, executorGauge.peak)
+		}
+	})
+}
+
+func TestCancelMode(t *testing.T) {
+	nextNewcomer := runRouter()
+
+	executorSession, executorPeer := joinSessionPeer(nextNewcomer)
+	_, callerPeer := joinSessionPeer(nextNewcomer)
+
+	killed := make(chan string, 8)
+	// local executor stops procedure when cancel event arrives
+	cancellable := func(payload string, callEvent wamp.CallEvent) (string, error) {
+		cancelEventPromise, cancelPromise := executorPeer.PendingCancelEvents.New(callEvent.ID(), 0)
+		defer cancelPromise()
+
+		select {
+		case <-cancelEventPromise:
+			killed <- payload
+			return "", errors.New("Killed")
+		case <-time.After(2 * time.Second):
+			return "Done!", nil
+		}
+	}
+
+	// sends call and cancel events manually, because session drops reply after cancel
+	callAndCancel := func(uri string, payload string, timeout uint64) (wamp.ReplyEvent, time.Duration) {
+		callEvent := wamp.MakeCallEvent(
+			wampShared.NewID(),
+			&wamp.CallFeatures{URI: uri, Timeout: timeout},
+			payload,
+			new(wamp.CallRoute),
+		)
+		replyEventPromise, _ := callerPeer.PendingReplyEvents.New(callEvent.ID(), 10*time.Second)
+		if !callerPeer.Send(callEvent, wamp.DEFAULT_RESEND_COUNT) {
+			t.Fatal("call event dispatch error")
+		}
+
+		time.Sleep(200 * time.Millisecond)
+
+		startedAt := time.Now()
+		cancelEvent := wamp.MakeCancelEvent(
+			wampShared.NewID(),
+			&wamp.ReplyFeatures{InvocationID: callEvent.ID()},
+		)
+		if !callerPeer.Send(cancelEvent, wamp.DEFAULT_RESEND_COUNT) {
+			t.Fatal("cancel event dispatch error")
+		}
+
+		replyEvent, ok := <-replyEventPromise
+		if !ok {
+			t.Fatal("reply expected")
+		}
+		return replyEvent, time.Since(startedAt)
+	}
+
+	readError := func(replyEvent wamp.ReplyEvent) string {
+		_, e := wamp.ReadPayload[string](replyEvent)
+		if e == nil {
+			return ""
+		}
+		return e.Error()
+	}
+
+	for _, mode := range []string{routerShared.CANCEL_SKIP, routerShared.CANCEL_KILL, routerShared.CANCEL_KILLNOWAIT} {
+		_, e := registerWithOptions(
+			executorSession,
+			"net.example."+mode,
+			&routerShared.RegisterOptions{Cancel: mode},
+			cancellable,
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+	}
+
+	t.Run("Case: Skip", func(t *testing.T) {
+		replyEvent, elapsed := callAndCancel("net.example.skip", "skip", wamp.DEFAULT_TIMEOUT)
+		if readError(replyEvent) != wamp.ErrorCancelled.Error() || elapsed > time.Second {
+			t.Fatalf("expected immediate %s, but got %s after %s", wamp.ErrorCancelled, readError(replyEvent), elapsed)
+		}
+		select {
+		case <-killed:
+			t.Fatal("executor must not be notified")
+		case <-time.After(time.Second):
+		}
+	})
+
+	t.Run("Case: Kill", func(t *testing.T) {
+		replyEvent, elapsed := callAndCancel("net.example.kill", "kill", wamp.DEFAULT_TIMEOUT)
+		if readError(replyEvent) != "Killed" || elapsed > time.Second {
+			t.Fatalf("expected executor error Killed, but got %s after %s", readError(replyEvent), elapsed)
+		}
+		if <-killed != "kill" {
+			t.Fatal("executor must be notified")
+		}
+	})
+
+	t.Run("Case: Kill no wait", func(t *testing.T) {
+		replyEvent, elapsed := callAndCancel("net.example.killnowait", "killnowait", wamp.DEFAULT_TIMEOUT)
+		if readError(replyEvent) != wamp.ErrorCancelled.Error() || elapsed > time.Second {
+			t.Fatalf("expected immediate %s, but got %s after %s", wamp.ErrorCancelled, readError(replyEvent), elapsed)
+		}
+		select {
+		case payload := <-killed:
+			if payload != "killnowait" {
+				t.Fatalf("unexpected killed call %s", payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("executor must be notified")
+		}
+	})
+
+	t.Run("Case: Skip without reply", func(t *testing.T) {
+		silentSession := joinSession(nextNewcomer)
+		released := make(chan struct{})
+		defer close(released)
+		started := new(atomic.Bool)
+		// first call never gets reply while executor stays connected
+		_, e := registerWithOptions(
+			silentSession,
+			"net.example.silent",
+			&routerShared.RegisterOptions{Cancel: routerShared.CANCEL_SKIP, Concurrency: 1},
+			func(payload string, callEvent wamp.CallEvent) (string, error) {
+				if started.CompareAndSwap(false, true) {
+					<-released
+				}
+				return payload, nil
+			},
+		)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+
+		replyEvent, _ := callAndCancel("net.example.silent", "silent", 1)
+		if readError(replyEvent) != wamp.ErrorCancelled.Error() {
+			t.Fatalf("expected %s, but got %s", wamp.ErrorCancelled, readError(replyEvent))
+		}
+
+		// executor is released when cancelled call times out
+		pendingResponse := wamp.Call[string](
+			executorSession,
+			&wamp.CallFeatures{URI: "net.example.silent", Timeout: 3},
+			"Hello!",
+		)
+		_, result, e := pendingResponse.Await()
+		if e != nil || result != "Hello!" {
+			t.Fatalf("RPC expected Hello!, but got %v (error=%v)", result, e)
+		}
+	})
+
+	t.Run("Case: Invalid", func(t *testing.T) {
+		_, e := registerWithOptions(
+			executorSession,
+			"net.example.invalid_cancel",
+			&routerShared.RegisterOptions{Cancel: "unknown"},
+			cancellable,
+		)
+		if e == nil || e.Error() != router.ErrorInvalidCancelMode.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorInvalidCancelMode, e)
+		}
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	routerID := wampShared.NewID()
+	authorizer, e := routerShared.NewAuthorizer(
+		routerShared.Rules{
+			"operator": {
+				{URI: "wamp.router.**", Actions: []string{routerShared.ACTION_CALL}},
+				{URI: "net.example.**", Actions: []string{routerShared.ACTION_ANY}},
+			},
+			"guest": {
+				{URI: "wamp.router.**", Actions: []string{routerShared.ACTION_CALL}},
+				{URI: "net.example.public.**", Actions: []string{routerShared.ACTION_CALL, routerShared.ACTION_SUBSCRIBE}},
+				{URI: "net.example.feed.*", Actions: []string{routerShared.ACTION_SUBSCRIBE}},
+			},
+		},
+		slog.Default(),
+	)
+	if e != nil {
+		t.Fatalf("new authorizer error %s", e)
+	}
+	nextNewcomer := spawnRouter(routerID, router.DEFAULT_CALL_WORKERS_COUNT, authorizer).Newcomers
+
+	operatorSession := joinSession(nextNewcomer)
+	authorizer.Assign(operatorSession.ID(), "operator")
+	guestSession := joinSession(nextNewcomer)
+	authorizer.Assign(guestSession.ID(), "guest")
+	anonymousSession := joinSession(nextNewcomer)
+
+	echo := func(payload string, callEvent wamp.CallEvent) (string, error) {
+		return payload, nil
+	}
+	call := func(session *wamp.Session, uri string) error {
+		_, _, e := wamp.Call[string](session, &wamp.CallFeatures{URI: uri}, "hello").Await()
+		return e
+	}
+	expectNotAuthorized := func(t *testing.T, e error) {
+		if e == nil || e.Error() != routerShared.ErrorNotAuthorized.Error() {
+			t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+		}
+	}
+
+	for _, uri := range []string{"net.example.private.echo", "net.example.public.echo"} {
+		_, e := wamp.Register(operatorSession, uri, &wamp.RegisterOptions{}, echo)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+	}
+
+	t.Run("Case: Register", func(t *testing.T) {
+		_, e := wamp.Register(guestSession, "net.example.public.guest", &wamp.RegisterOptions{}, echo)
+		expectNotAuthorized(t, e)
+	})
+
+	t.Run("Case: Call", func(t *testing.T) {
+		e := call(guestSession, "net.example.public.echo")
+		if e != nil {
+			t.Fatalf("call error %s", e)
+		}
+		expectNotAuthorized(t, call(guestSession, "net.example.private.echo"))
+		expectNotAuthorized(t, call(anonymousSession, "net.example.public.echo"))
+	})
+
+	t.Run("Case: Subscribe", func(t *testing.T) {
+		_, e := wamp.Subscribe(
+			guestSession,
+			"net.example.private.news",
+			&wamp.SubscribeOptions{},
+			func(message string, publishEvent wamp.PublishEvent) {},
+		)
+		expectNotAuthorized(t, e)
+
+		subscribe := func(uri string, policy string) error {
+			_, _, e := wamp.Call[*wamp.Subscription](
+				guestSession,
+				&wamp.CallFeatures{URI: "wamp.router.subscribe"},
+				wamp.NewResourcePayload[routerShared.SubscribeOptions]{
+					URI:     uri,
+					Options: &routerShared.SubscribeOptions{Match: policy},
+				},
+			).Await()
+			return e
+		}
+		e = subscribe("net.example.feed.*", routerShared.MATCH_WILDCARD)
+		if e != nil {
+			t.Fatalf("subscribe error %s", e)
+		}
+		// pattern must not reach further than rule does
+		expectNotAuthorized(t, subscribe("net.example.feed.**", routerShared.MATCH_WILDCARD))
+		expectNotAuthorized(t, subscribe("net.example.feed", routerShared.MATCH_PREFIX))
+	})
+
+	t.Run("Case: Publish", func(t *testing.T) {
+		received := make(chan string, 2)
+		_, e := wamp.Subscribe(
+			operatorSession,
+			"net.example.public.news",
+			&wamp.SubscribeOptions{},
+			func(message string, publishEvent wamp.PublishEvent) { received <- message },
+		)
+		if e != nil {
+			t.Fatalf("subscribe error %s", e)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		wamp.Publish(guestSession, &wamp.PublishFeatures{URI: "net.example.public.news"}, "guest")
+		wamp.Publish(anonymousSession, &wamp.PublishFeatures{URI: "net.example.public.news"}, "anonymous")
+		select {
+		case message := <-received:
+			t.Fatalf("unauthorized publication %s delivered", message)
+		case <-time.After(300 * time.Millisecond):
+		}
+
+		_, e = wamp.Subscribe(
+			guestSession,
+			"net.example.public.news",
+			&wamp.SubscribeOptions{},
+			func(message string, publishEvent wamp.PublishEvent) { received <- message },
+		)
+		if e != nil {
+			t.Fatalf("subscribe error %s", e)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		wamp.Publish(operatorSession, &wamp.PublishFeatures{URI: "net.example.public.news"}, "operator")
+		select {
+		case message := <-received:
+			if message != "operator" {
+				t.Fatalf("unexpected publication %s", message)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("publication expected")
+		}
+	})
+}
+
+func TestAuthorizationHook(t *testing.T) {
+	routerID := wampShared.NewID()
+	__router := spawnRouter(routerID, router.DEFAULT_CALL_WORKERS_COUNT, nil)
+	nextNewcomer := __router.Newcomers
+
+	hookSession := joinSession(nextNewcomer)
+	guestSession := joinSession(nextNewcomer)
+	__router.Authorizer.Assign(guestSession.ID(), "guest")
+
+	echo := func(payload string, callEvent wamp.CallEvent) (string, error) {
+		return payload, nil
+	}
+	call := func(uri string) error {
+		_, _, e := wamp.Call[string](guestSession, &wamp.CallFeatures{URI: uri}, "hello").Await()
+		return e
+	}
+
+	for _, uri := range []string{"net.example.private.echo", "net.example.public.echo"} {
+		_, e := wamp.Register(hookSession, uri, &wamp.RegisterOptions{}, echo)
+		if e != nil {
+			t.Fatalf("register error %s", e)
+		}
+	}
+
+	// patterns which match hook are not consulted
+	patternRequests := make(chan routerShared.AuthorizationRequest, 16)
+	_, e := registerWithOptions(
+		guestSession,
+		routerShared.AUTHORIZE_URI,
+		&routerShared.RegisterOptions{Match: routerShared.MATCH_PREFIX},
+		func(request routerShared.AuthorizationRequest, callEvent wamp.CallEvent) (bool, error) {
+			patternRequests <- request
+			return false, nil
+		},
+	)
+	if e != nil {
+		t.Fatalf("register error %s", e)
+	}
+
+	// everything is allowed until hook is registered
+	e = call("net.example.private.echo")
+	if e != nil {
+		t.Fatalf("call error %s", e)
+	}
+
+	requests := make(chan routerShared.AuthorizationRequest, 16)
+	_, e = wamp.Register(
+		hookSession,
+		routerShared.AUTHORIZE_URI,
+		&wamp.RegisterOptions{},
+		func(request routerShared.AuthorizationRequest, callEvent wamp.CallEvent) (bool, error) {
+			if request.PeerID == guestSession.ID() {
+				requests <- request
+			}
+			return request.PeerID == hookSession.ID() || strings.HasPrefix(request.URI, "net.example.public."), nil
+		},
+	)
+	if e != nil {
+		t.Fatalf("register error %s", e)
+	}
+
+	for i := 0; i < 2; i++ {
+		e = call("net.example.public.echo")
+		if e != nil {
+			t.Fatalf("call error %s", e)
+		}
+	}
+	e = call("net.example.private.echo")
+	if e == nil || e.Error() != routerShared.ErrorNotAuthorized.Error() {
+		t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+	}
+
+	// second call is decided by cache
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 hook requests, but got %d", len(requests))
+	}
+	request := <-requests
+	if request.Role != "guest" || request.Action != routerShared.ACTION_CALL || request.URI != "net.example.public.echo" {
+		t.Fatalf("unexpected hook request %+v", request)
+	}
+	if len(patternRequests) != 0 {
+		t.Fatalf("pattern registration got %d hook requests", len(patternRequests))
+	}
+}
+
+func TestGenerateTicket(t *testing.T) {
+	routerID := wampShared.NewID()
+	__router := spawnRouter(routerID, router.DEFAULT_CALL_WORKERS_COUNT, nil)
+	nextNewcomer := __router.Newcomers
+
+	localSession := joinSession(nextNewcomer)
+	__router.Authorizer.Trust(localSession.ID())
+	guestSession := joinSession(nextNewcomer)
+	// role of claims does not make peer local
+	forgedSession := joinSession(nextNewcomer)
+	__router.Authorizer.Assign(forgedSession.ID(), routerShared.ROLE_LOCAL)
+
+	generate := func(session *wamp.Session, payload router.GenerateTicketPayload) (string, error) {
+		_, ticket, e := wamp.Call[string](
+			session,
+			&wamp.CallFeatures{URI: "wamp.ticket.generate"},
+			payload,
+		).Await()
+		return ticket, e
+	}
+
+	ticket, e := generate(localSession, router.GenerateTicketPayload{"alpha", time.Hour, "operator"})
+	if e != nil {
+		t.Fatalf("generate ticket error %s", e)
+	}
+	claims, e := __router.KeyRing.JWTParse(ticket)
+	if e != nil {
+		t.Fatalf("parse ticket error %s", e)
+	}
+	if claims.Issuer != routerID || claims.Subject != "alpha" || claims.Role != "operator" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+
+	_, e = generate(localSession, router.GenerateTicketPayload{"alpha", 0, ""})
+	if e == nil || e.Error() != wamp.ErrorInvalidPayload.Error() {
+		t.Fatalf("expected %s, but got %v", wamp.ErrorInvalidPayload, e)
+	}
+
+	_, e = generate(localSession, router.GenerateTicketPayload{"alpha", time.Hour, routerShared.ROLE_LOCAL})
+	if e == nil || e.Error() != routerShared.ErrorReservedRole.Error() {
+		t.Fatalf("expected %s, but got %v", routerShared.ErrorReservedRole, e)
+	}
+
+	for _, peerID := range []string{routerID, guestSession.ID()} {
+		_, e = generate(localSession, router.GenerateTicketPayload{peerID, time.Hour, ""})
+		if e == nil || e.Error() != router.ErrorPeerAlreadyExists.Error() {
+			t.Fatalf("expected %s, but got %v", router.ErrorPeerAlreadyExists, e)
+		}
+	}
+
+	for _, session := range []*wamp.Session{guestSession, forgedSession} {
+		_, e = generate(session, router.GenerateTicketPayload{"alpha", time.Hour, ""})
+		if e == nil || e.Error() != routerShared.ErrorNotAuthorized.Error() {
+			t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+		}
+	}
 }
@@ -1,26 +1,78 @@
 package routerServers
 
 import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"os"
 
 	wamp "github.com/wamp3hub/wamp3go"
 )
 
+const (
+	// calls `wamp.authenticate` if it has been registered, otherwise everyone is anonymous
+	AUTHENTICATION_MODE_OPEN = "open"
+	// calls `wamp.authenticate`, rejects everyone until it has been registered
+	AUTHENTICATION_MODE_DYNAMIC = "dynamic"
+	// checks credentials against static credentials file
+	AUTHENTICATION_MODE_STATIC = "static"
+)
+
+var (
+	ErrorUnknownAuthenticationMode = errors.New("UnknownAuthenticationMode")
+	// backend of authentication mode is not able to make decision
+	ErrorAuthenticatorUnavailable = errors.New("AuthenticatorUnavailable")
+	ErrorInvalidCredentials       = errors.New("InvalidCredentials")
+)
+
 type Authenticator interface {
-	authenticate() error
+	// returns role of peer
+	authenticate(session *wamp.Session, credentials any) (string, error)
+}
+
+// returns names of available authentication modes
+func AuthenticationModes() []string {
+	return []string{AUTHENTICATION_MODE_OPEN, AUTHENTICATION_MODE_DYNAMIC, AUTHENTICATION_MODE_STATIC}
+}
+
+// creates authenticator by mode name, credentials path is used by static mode only
+func NewAuthenticator(
+	mode string,
+	credentialsPath string,
+	session *wamp.Session,
+	logger *slog.Logger,
+) (Authenticator, error) {
+	switch mode {
+	case AUTHENTICATION_MODE_OPEN:
+		return NewDynamicAuthenticator(session, false, logger), nil
+	case AUTHENTICATION_MODE_DYNAMIC:
+		return NewDynamicAuthenticator(session, true, logger), nil
+	case AUTHENTICATION_MODE_STATIC:
+		credentialsList, e := ReadCredentials(credentialsPath)
+		if e != nil {
+			return nil, e
+		}
+		return NewStaticAuthenticator(credentialsList, logger), nil
+	}
+	return nil, ErrorUnknownAuthenticationMode
 }
 
 type DynamicAuthenticator struct {
 	session *wamp.Session
-	logger  *slog.Logger
+	// rejects peers if `wamp.authenticate` has not been registered
+	required bool
+	logger   *slog.Logger
 }
 
 func NewDynamicAuthenticator(
 	session *wamp.Session,
+	required bool,
 	logger *slog.Logger,
 ) *DynamicAuthenticator {
 	return &DynamicAuthenticator{
 		session,
+		required,
 		logger.With("name", "DynamicAuthenticator"),
 	}
 }
@@ -28,7 +80,7 @@ func NewDynamicAuthenticator(
 func (authenticator *DynamicAuthenticator) authenticate(
 	session *wamp.Session,
 	credentials any,
-) error {
+) (string, error) {
 	pendingResponse := wamp.Call[string](
 		session,
 		&wamp.CallFeatures{URI: "wamp.authenticate"},
@@ -37,10 +89,90 @@ func (authenticator *DynamicAuthenticator) authenticate(
 	_, role, e := pendingResponse.Await()
 	if e == nil {
 		authenticator.logger.Info("authentication success", "Role", role)
-		return nil
+		return role, nil
 	} else if e.Error() == wamp.ErrorProcedureNotFound.Error() {
+		if authenticator.required {
+			authenticator.logger.Error("`wamp.authenticate` has not been registered, peer rejected")
+			return "", ErrorAuthenticatorUnavailable
+		}
 		authenticator.logger.Warn("please, register `wamp.authenticate`")
-		return nil
+		return "", nil
+	}
+	return "", e
+}
+
+// expected credentials of peer, role is granted by file and ignored in credentials of peer
+type StaticCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// format of credentials file
+type CredentialsConfig struct {
+	Credentials []StaticCredentials `json:"credentials"`
+}
+
+// reads credentials from json file, access to file must be restricted because passwords are stored as is
+func ReadCredentials(path string) ([]StaticCredentials, error) {
+	bytes, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	config := new(CredentialsConfig)
+	e = json.Unmarshal(bytes, config)
+	if e != nil {
+		return nil, e
+	}
+	for _, credentials := range config.Credentials {
+		if len(credentials.Username) == 0 || len(credentials.Password) == 0 {
+			return nil, ErrorInvalidCredentials
+		}
+	}
+	return config.Credentials, nil
+}
+
+type StaticAuthenticator struct {
+	credentials map[string]StaticCredentials
+	logger      *slog.Logger
+}
+
+func NewStaticAuthenticator(
+	credentialsList []StaticCredentials,
+	logger *slog.Logger,
+) *StaticAuthenticator {
+	credentials := map[string]StaticCredentials{}
+	for _, item := range credentialsList {
+		credentials[item.Username] = item
+	}
+	return &StaticAuthenticator{
+		credentials,
+		logger.With("name", "StaticAuthenticator"),
 	}
-	return e
+}
+
+func (authenticator *StaticAuthenticator) authenticate(
+	session *wamp.Session,
+	credentials any,
+) (string, error) {
+	// credentials are decoded from json as map, so they are converted back
+	rawCredentials, e := json.Marshal(credentials)
+	if e != nil {
+		return "", ErrorInvalidCredentials
+	}
+	given := new(StaticCredentials)
+	e = json.Unmarshal(rawCredentials, given)
+	if e != nil {
+		return "", ErrorInvalidCredentials
+	}
+
+	expected, found := authenticator.credentials[given.Username]
+	if !found || subtle.ConstantTimeCompare([]byte(given.Password), []byte(expected.Password)) != 1 {
+		authenticator.logger.Warn("authentication failure", "Username", given.Username)
+		return "", ErrorInvalidCredentials
+	}
+
+	authenticator.logger.Info("authentication success", "Username", given.Username, "Role", expected.Role)
+	return expected.Role, nil
 }
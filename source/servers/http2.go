@@ -47,6 +47,7 @@ type HTTP2Server struct {
 	EnableWebsocket bool
 	Address         string
 	router          *router.Router
+	authenticator   Authenticator
 	logger          *slog.Logger
 	super           *http.Server
 }
@@ -55,12 +56,14 @@ func NewHTTP2Server(
 	address string,
 	enableWebsocket bool,
 	router *router.Router,
+	authenticator Authenticator,
 	logger *slog.Logger,
 ) *HTTP2Server {
 	return &HTTP2Server{
 		enableWebsocket,
 		address,
 		router,
+		authenticator,
 		logger.With("name", "HTTP2Server"),
 		&http.Server{},
 	}
@@ -71,12 +74,22 @@ func (server *HTTP2Server) Serve() error {
 
 	serveMux.Handle(
 		"/wamp/v1/interview",
-		http2interviewMount(server.router.Session, server.router.KeyRing, server.logger),
+		http2interviewMount(
+			server.router.Session,
+			server.router.KeyRing,
+			server.authenticator,
+			server.logger,
+		),
 	)
 	if server.EnableWebsocket {
 		serveMux.Handle(
 			"/wamp/v1/websocket",
-			http2websocketMount(server.router.KeyRing, server.router.Newcomers, server.logger),
+			http2websocketMount(
+				server.router.KeyRing,
+				server.router.Authorizer,
+				server.router.Newcomers,
+				server.logger,
+			),
 		)
 	}
 
@@ -1,6 +1,7 @@
 package routerServers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -16,10 +17,10 @@ import (
 func http2interviewMount(
 	session *wamp.Session,
 	keyRing *routerShared.KeyRing,
+	authenticator Authenticator,
 	__logger *slog.Logger,
 ) http.Handler {
 	logger := __logger.With("server", "http2interview")
-	authenticator := NewDynamicAuthenticator(session, __logger)
 
 	onInterview := func(request *http.Request) (int, any) {
 		if request.Method == "OPTIONS" {
@@ -33,18 +34,28 @@ func http2interviewMount(
 			return 400, e
 		}
 
-		e = authenticator.authenticate(session, requestPayload.Credentials)
-		if e != nil {
+		role, e := authenticator.authenticate(session, requestPayload.Credentials)
+		if errors.Is(e, ErrorAuthenticatorUnavailable) {
+			logger.Error("during authentication", "error", e)
+			return 503, e
+		} else if e != nil {
 			logger.Error("during authentication", "error", e)
 			return 400, e
+		} else if role == routerShared.ROLE_LOCAL {
+			// local role is granted by unix transport only
+			logger.Error("reserved role granted by authentication, peer rejected", "Role", role)
+			return 403, routerShared.ErrorReservedRole
 		}
 
 		now := time.Now()
 		claims := routerShared.JWTClaims{
-			Issuer:    session.ID(),
-			Subject:   session.ID() + "-" + wampShared.NewID(),
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
-			IssuedAt:  jwt.NewNumericDate(now),
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    session.ID(),
+				Subject:   session.ID() + "-" + wampShared.NewID(),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+			Role: role,
 		}
 		ticket, _ := keyRing.JWTSign(&claims)
 		responsePayload := wampInterview.SuccessPayload{
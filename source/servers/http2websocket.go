@@ -16,6 +16,7 @@ import (
 
 func http2websocketMount(
 	keyRing *routerShared.KeyRing,
+	authorizer *routerShared.Authorizer,
 	newcomers *wampShared.Observable[*wamp.Peer],
 	__logger *slog.Logger,
 ) http.Handler {
@@ -46,6 +47,7 @@ func http2websocketMount(
 				}
 				resumableTransport := wampTransports.MakeResumable(&transport)
 				peer := wamp.SpawnPeer(claims.Subject, resumableTransport, logger)
+				authorizer.Assign(peer.ID, claims.Role)
 				newcomers.Next(peer)
 				logger.Info("new peer", "ID", peer.ID)
 			} else {
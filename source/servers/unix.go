@@ -2,6 +2,7 @@ package routerServers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net"
 	"os"
@@ -33,6 +34,22 @@ func NewUnixServer(
 	}
 }
 
+// unix clients never reconnect, so broken connection means peer has gone
+type unixPeerTransport struct {
+	wamp.Transport
+	// peer drops events which arrive before router observes it, so reading waits for attachment
+	attached <-chan struct{}
+}
+
+func (transport unixPeerTransport) Read() (wamp.Event, error) {
+	<-transport.attached
+	event, e := transport.Transport.Read()
+	if errors.Is(e, wampTransports.ErrorBadConnection) {
+		return nil, wamp.ErrorConnectionClosed
+	}
+	return event, e
+}
+
 func (server *UnixServer) onConnect(
 	connection net.Conn,
 ) error {
@@ -51,9 +68,12 @@ func (server *UnixServer) onConnect(
 			clientMessage := new(wampTransports.UnixClientMessage)
 			e = json.Unmarshal(rawClientMessage, clientMessage)
 			if e == nil {
-				peer := wamp.SpawnPeer(serverMessage.YourID, transport, server.logger)
+				attached := make(chan struct{})
+				peer := wamp.SpawnPeer(serverMessage.YourID, unixPeerTransport{transport, attached}, server.logger)
 				server.logger.Info("new peer", "ID", peer.ID)
+				server.router.Authorizer.Trust(peer.ID)
 				server.router.Newcomers.Next(peer)
+				close(attached)
 			}
 		}
 	}
@@ -0,0 +1,546 @@
+package routerShared
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+const (
+	ACTION_CALL      = "call"
+	ACTION_REGISTER  = "register"
+	ACTION_PUBLISH   = "publish"
+	ACTION_SUBSCRIBE = "subscribe"
+	ACTION_ANY       = "*"
+)
+
+const (
+	// role of peers which have not got role during authentication
+	ROLE_ANONYMOUS = "anonymous"
+	// role of peers connected through unix socket, access to socket is guarded by file permissions,
+	// role alone grants nothing, such peers are trusted by transport
+	ROLE_LOCAL = "local"
+)
+
+var actions = NewSet([]string{ACTION_CALL, ACTION_REGISTER, ACTION_PUBLISH, ACTION_SUBSCRIBE, ACTION_ANY})
+
+var (
+	ErrorNotAuthorized = errors.New("wamp.error.not_authorized")
+	ErrorInvalidAction = errors.New("InvalidAction")
+	// role which may be granted only by transport
+	ErrorReservedRole = errors.New("ReservedRole")
+	// hook has not been registered, so static rules decide
+	ErrorHookUnavailable = errors.New("HookUnavailable")
+)
+
+// procedure which is registered by peer to make authorization decisions
+const AUTHORIZE_URI = "wamp.authorize"
+
+// how long decisions of hook are kept by default
+const DEFAULT_AUTHORIZATION_TTL = time.Minute
+
+// payload of `wamp.authorize`, procedure returns true if action is allowed
+type AuthorizationRequest struct {
+	PeerID string `json:"peerID"`
+	Role   string `json:"role"`
+	Action string `json:"action"`
+	URI    string `json:"URI"`
+}
+
+type AuthorizationHook func(request AuthorizationRequest) (bool, error)
+
+type decision struct {
+	allowed   bool
+	expiresAt time.Time
+}
+
+// decisions of hook made for single session
+type decisionCache struct {
+	mutex   sync.Mutex
+	entries map[string]decision
+}
+
+// allows actions on resources which URIs match the pattern
+type Permission struct {
+	URI     string   `json:"URI"`
+	Match   string   `json:"match"`
+	Actions []string `json:"actions"`
+}
+
+func (permission *Permission) MatchPolicy() string {
+	if len(permission.Match) == 0 {
+		return DEFAULT_MATCH_POLICY
+	}
+	return permission.Match
+}
+
+// permissions of each role
+type Rules map[string][]Permission
+
+// format of rules file
+type RulesConfig struct {
+	Roles Rules `json:"roles"`
+}
+
+// reads rules from json file, validates them as `Authorizer.Load` does
+func ReadRules(path string) (Rules, error) {
+	bytes, e := os.ReadFile(path)
+	if e != nil {
+		return nil, e
+	}
+
+	config := new(RulesConfig)
+	e = json.Unmarshal(bytes, config)
+	if e != nil {
+		return nil, e
+	}
+	if config.Roles == nil {
+		// nil rules allow everything, while missing roles must allow nothing
+		config.Roles = Rules{}
+	}
+
+	for _, permissionList := range config.Roles {
+		_, e = newRolePermissions(permissionList)
+		if e != nil {
+			return nil, e
+		}
+	}
+	return config.Roles, nil
+}
+
+type regexPermission struct {
+	expression *regexp.Regexp
+	actions    *Set[string]
+}
+
+const (
+	segmentLiteral = iota
+	// `*` of wildcard pattern
+	segmentOne
+	// `**` of wildcard pattern or tail of prefix pattern
+	segmentMany
+)
+
+type patternSegment struct {
+	kind  int
+	value string
+}
+
+// returns segments of pattern the way its match policy reads them, e.g. `*` is literal in exact pattern
+func parsePattern(uri string, policy string) ([]patternSegment, error) {
+	path, e := ParseURI(uri)
+	if e != nil {
+		return nil, e
+	}
+
+	segmentList := []patternSegment{}
+	for _, value := range path {
+		kind := segmentLiteral
+		if policy == MATCH_WILDCARD && value == WILD_CARD_SYMBOL {
+			kind = segmentOne
+		} else if policy == MATCH_WILDCARD && value == MULTI_WILD_CARD_SYMBOL {
+			kind = segmentMany
+		}
+		segmentList = append(segmentList, patternSegment{kind, value})
+	}
+	if policy == MATCH_PREFIX {
+		segmentList = append(segmentList, patternSegment{segmentMany, MULTI_WILD_CARD_SYMBOL})
+	}
+	return segmentList, nil
+}
+
+// returns true if every URI which `request` matches is matched by `rule` too,
+// may deny some covered patterns, but never allows uncovered ones
+func covers(request []patternSegment, rule []patternSegment) bool {
+	visited := map[[2]int]bool{}
+	var cover func(i int, j int) bool
+	cover = func(i int, j int) bool {
+		key := [2]int{i, j}
+		result, found := visited[key]
+		if found {
+			return result
+		}
+
+		if j < len(rule) && rule[j].kind == segmentMany && cover(i, j+1) {
+			// `**` of rule matches nothing
+			result = true
+		} else if i == len(request) {
+			result = j == len(rule)
+		} else if j < len(rule) {
+			switch rule[j].kind {
+			case segmentMany:
+				// `**` of rule absorbs segment of request
+				result = cover(i+1, j)
+			case segmentOne:
+				result = request[i].kind != segmentMany && cover(i+1, j+1)
+			default:
+				result = request[i].kind == segmentLiteral && request[i].value == rule[j].value && cover(i+1, j+1)
+			}
+		}
+
+		visited[key] = result
+		return result
+	}
+	return cover(0, 0)
+}
+
+type patternPermission struct {
+	segmentList []patternSegment
+	actions     *Set[string]
+}
+
+func (permission *patternPermission) grants(action string) bool {
+	return permission.actions.Contains(action) || permission.actions.Contains(ACTION_ANY)
+}
+
+// permissions of single role indexed like URIM, segment data is keyed by action,
+// `patterns` keep the same permissions to check patterns of registrations and subscriptions
+type rolePermissions struct {
+	exact    *URISegment[struct{}]
+	prefix   *URISegment[struct{}]
+	wildcard *URISegment[struct{}]
+	regex    []regexPermission
+	patterns []patternPermission
+}
+
+func newRolePermissions(permissionList []Permission) (*rolePermissions, error) {
+	role := &rolePermissions{
+		NewURISegment[struct{}](nil),
+		NewURISegment[struct{}](nil),
+		NewURISegment[struct{}](nil),
+		[]regexPermission{},
+		[]patternPermission{},
+	}
+	for _, permission := range permissionList {
+		for _, action := range permission.Actions {
+			if !actions.Contains(action) {
+				return nil, ErrorInvalidAction
+			}
+		}
+
+		policy := permission.MatchPolicy()
+		if policy == MATCH_REGEX {
+			expression, e := regexp.Compile(permission.URI)
+			if e != nil {
+				return nil, ErrorInvalidURI
+			}
+			role.regex = append(role.regex, regexPermission{expression, NewSet(permission.Actions)})
+			continue
+		}
+
+		var root *URISegment[struct{}]
+		switch policy {
+		case MATCH_EXACT:
+			root = role.exact
+		case MATCH_PREFIX:
+			root = role.prefix
+		case MATCH_WILDCARD:
+			root = role.wildcard
+		default:
+			return nil, ErrorInvalidMatchPolicy
+		}
+
+		path, e := ParseURI(permission.URI)
+		if e != nil {
+			return nil, e
+		}
+		segment := root.GetSert(path)
+		for _, action := range permission.Actions {
+			segment.Data[action] = struct{}{}
+		}
+
+		segmentList, _ := parsePattern(permission.URI, policy)
+		role.patterns = append(role.patterns, patternPermission{segmentList, NewSet(permission.Actions)})
+	}
+	return role, nil
+}
+
+func (role *rolePermissions) allows(action string, uri string) bool {
+	path, e := ParseURI(uri)
+	if e != nil {
+		return false
+	}
+
+	segmentList := URISegmentList[struct{}]{}
+	segment := role.exact.Get(path)
+	if segment != nil {
+		segmentList = append(segmentList, segment)
+	}
+	segmentList = append(segmentList, role.prefix.MatchPrefix(path)...)
+	segmentList = append(segmentList, role.wildcard.Match(path)...)
+	for _, segment := range segmentList {
+		_, found := segment.Data[action]
+		if found {
+			return true
+		}
+		_, found = segment.Data[ACTION_ANY]
+		if found {
+			return true
+		}
+	}
+
+	for _, permission := range role.regex {
+		if permission.expression.MatchString(uri) &&
+			(permission.actions.Contains(action) || permission.actions.Contains(ACTION_ANY)) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns true if permission of some rule covers every URI which pattern of the policy matches
+func (role *rolePermissions) allowsPattern(action string, uri string, policy string) bool {
+	if policy == MATCH_EXACT {
+		return role.allows(action, uri)
+	}
+
+	if policy == MATCH_REGEX {
+		// regular expressions are not compared, so only the same expression is covered
+		for _, permission := range role.regex {
+			if permission.expression.String() == uri &&
+				(permission.actions.Contains(action) || permission.actions.Contains(ACTION_ANY)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	request, e := parsePattern(uri, policy)
+	if e != nil {
+		return false
+	}
+	for _, permission := range role.patterns {
+		if permission.grants(action) && covers(request, permission.segmentList) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decides whether peer may perform action on URI.
+// Peers trusted by transport, like router itself and unix socket peers, are allowed everything.
+// Hook decides when it is available, otherwise static rules of peer role decide,
+// roles which have no rules are allowed nothing, nil rules allow everything.
+// Nil authorizer allows everything. Safe for concurrent use
+type Authorizer struct {
+	mutex        sync.RWMutex
+	roles        map[string]*rolePermissions
+	peerRoles    cmap.ConcurrentMap[string, string]
+	trustedPeers cmap.ConcurrentMap[string, struct{}]
+	hook         AuthorizationHook
+	hookTTL      time.Duration
+	decisions    cmap.ConcurrentMap[string, *decisionCache]
+	logger       *slog.Logger
+}
+
+func NewAuthorizer(
+	rules Rules,
+	logger *slog.Logger,
+) (*Authorizer, error) {
+	authorizer := &Authorizer{
+		roles:        map[string]*rolePermissions{},
+		peerRoles:    cmap.New[string](),
+		trustedPeers: cmap.New[struct{}](),
+		decisions:    cmap.New[*decisionCache](),
+		logger:       logger.With("name", "Authorizer"),
+	}
+	e := authorizer.Load(rules)
+	return authorizer, e
+}
+
+// replaces rules, keeps previous rules if new ones are invalid
+func (authorizer *Authorizer) Load(rules Rules) error {
+	if rules == nil {
+		authorizer.mutex.Lock()
+		authorizer.roles = nil
+		authorizer.mutex.Unlock()
+
+		authorizer.logger.Info("static rules disabled")
+		return nil
+	}
+
+	roles := map[string]*rolePermissions{}
+	for name, permissionList := range rules {
+		role, e := newRolePermissions(permissionList)
+		if e != nil {
+			authorizer.logger.Error("invalid permission", "error", e, "Role", name)
+			return e
+		}
+		roles[name] = role
+	}
+
+	authorizer.mutex.Lock()
+	authorizer.roles = roles
+	authorizer.mutex.Unlock()
+
+	authorizer.logger.Info("rules loaded", "rolesCount", len(roles))
+	return nil
+}
+
+// remembers role of peer, empty role means anonymous,
+// role comes from claims, so peer which was trusted before is not trusted anymore
+func (authorizer *Authorizer) Assign(peerID string, role string) {
+	if authorizer == nil {
+		return
+	}
+	if role == ROLE_LOCAL {
+		// claims can be forged, only transport makes peer local
+		authorizer.logger.Warn("reserved role of claims ignored", "PeerID", peerID, "Role", role)
+		role = ROLE_ANONYMOUS
+	}
+	if len(role) == 0 {
+		role = ROLE_ANONYMOUS
+	}
+	authorizer.peerRoles.Set(peerID, role)
+	authorizer.trustedPeers.Remove(peerID)
+}
+
+// allows everything to peer and gives it local role,
+// must be called only by transports which guarantee identity of peer
+func (authorizer *Authorizer) Trust(peerID string) {
+	if authorizer == nil {
+		return
+	}
+	authorizer.peerRoles.Set(peerID, ROLE_LOCAL)
+	authorizer.trustedPeers.Set(peerID, struct{}{})
+}
+
+func (authorizer *Authorizer) Trusted(peerID string) bool {
+	if authorizer == nil {
+		return false
+	}
+	return authorizer.trustedPeers.Has(peerID)
+}
+
+func (authorizer *Authorizer) Forget(peerID string) {
+	if authorizer == nil {
+		return
+	}
+	authorizer.peerRoles.Remove(peerID)
+	authorizer.trustedPeers.Remove(peerID)
+	authorizer.decisions.Remove(peerID)
+}
+
+// decisions of hook are cached per session for `ttl`
+func (authorizer *Authorizer) UseHook(hook AuthorizationHook, ttl time.Duration) {
+	authorizer.mutex.Lock()
+	authorizer.hook = hook
+	authorizer.hookTTL = ttl
+	authorizer.mutex.Unlock()
+}
+
+func (authorizer *Authorizer) askHook(
+	hook AuthorizationHook,
+	ttl time.Duration,
+	request AuthorizationRequest,
+) (bool, error) {
+	key := request.Action + ":" + request.URI
+	cache := authorizer.decisions.Upsert(
+		request.PeerID,
+		nil,
+		func(exist bool, current *decisionCache, __ *decisionCache) *decisionCache {
+			if exist {
+				return current
+			}
+			return &decisionCache{entries: map[string]decision{}}
+		},
+	)
+
+	cache.mutex.Lock()
+	cached, found := cache.entries[key]
+	cache.mutex.Unlock()
+	if found && time.Now().Before(cached.expiresAt) {
+		return cached.allowed, nil
+	}
+
+	allowed, e := hook(request)
+	if e != nil {
+		return false, e
+	}
+
+	now := time.Now()
+	cache.mutex.Lock()
+	// expired decisions are dropped, otherwise cache grows with every URI peer touches
+	for other, cached := range cache.entries {
+		if now.After(cached.expiresAt) {
+			delete(cache.entries, other)
+		}
+	}
+	cache.entries[key] = decision{allowed, now.Add(ttl)}
+	cache.mutex.Unlock()
+	return allowed, nil
+}
+
+// returns anonymous role if peer has not been assigned
+func (authorizer *Authorizer) Role(peerID string) string {
+	if authorizer == nil {
+		return ROLE_ANONYMOUS
+	}
+	role, found := authorizer.peerRoles.Get(peerID)
+	if !found {
+		return ROLE_ANONYMOUS
+	}
+	return role
+}
+
+// returns ErrorNotAuthorized if peer may not perform action on URI
+func (authorizer *Authorizer) Authorize(peerID string, action string, uri string) error {
+	return authorizer.AuthorizePattern(peerID, action, uri, MATCH_EXACT)
+}
+
+// like `Authorize`, but URI is pattern of the match policy, e.g. URI of registration
+func (authorizer *Authorizer) AuthorizePattern(peerID string, action string, uri string, policy string) error {
+	if authorizer == nil || authorizer.Trusted(peerID) {
+		return nil
+	}
+
+	roleName := authorizer.Role(peerID)
+
+	authorizer.mutex.RLock()
+	roles := authorizer.roles
+	hook := authorizer.hook
+	hookTTL := authorizer.hookTTL
+	authorizer.mutex.RUnlock()
+
+	// hook must not approve registrations which would serve it
+	if hook != nil && !(action == ACTION_REGISTER && PatternMatch(uri, policy, AUTHORIZE_URI)) {
+		allowed, e := authorizer.askHook(hook, hookTTL, AuthorizationRequest{peerID, roleName, action, uri})
+		if e == nil && allowed {
+			return nil
+		} else if e == nil || !errors.Is(e, ErrorHookUnavailable) {
+			authorizer.logger.Warn(
+				"not authorized by hook",
+				"error", e,
+				"PeerID", peerID,
+				"Role", roleName,
+				"Action", action,
+				"URI", uri,
+			)
+			return ErrorNotAuthorized
+		}
+	}
+
+	if roles == nil {
+		return nil
+	}
+
+	role, found := roles[roleName]
+	if found && role.allowsPattern(action, uri, policy) {
+		return nil
+	}
+
+	authorizer.logger.Warn(
+		"not authorized",
+		"PeerID", peerID,
+		"Role", roleName,
+		"Action", action,
+		"URI", uri,
+	)
+	return ErrorNotAuthorized
+}
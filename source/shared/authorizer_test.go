@@ -0,0 +1,270 @@
+package routerShared_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+func TestAuthorizer(t *testing.T) {
+	rules := routerShared.Rules{
+		"operator": {
+			{URI: "net.example.**", Actions: []string{routerShared.ACTION_ANY}},
+		},
+		"guest": {
+			{URI: "net.example.public", Match: routerShared.MATCH_PREFIX, Actions: []string{routerShared.ACTION_CALL}},
+			{URI: "net.example.news", Match: routerShared.MATCH_EXACT, Actions: []string{routerShared.ACTION_SUBSCRIBE}},
+			{URI: `^net\.example\.chat\.[0-9]+$`, Match: routerShared.MATCH_REGEX, Actions: []string{routerShared.ACTION_PUBLISH}},
+		},
+	}
+	authorizer, e := routerShared.NewAuthorizer(rules, slog.Default())
+	if e != nil {
+		t.Fatalf("new authorizer error %s", e)
+	}
+	authorizer.Assign("alpha", "operator")
+	authorizer.Assign("beta", "guest")
+	authorizer.Assign("gamma", "")
+	authorizer.Trust("delta")
+	// local role of claims is ignored
+	authorizer.Assign("epsilon", routerShared.ROLE_LOCAL)
+
+	testCases := []struct {
+		peerID  string
+		action  string
+		uri     string
+		allowed bool
+	}{
+		{"alpha", routerShared.ACTION_REGISTER, "net.example.echo", true},
+		{"alpha", routerShared.ACTION_PUBLISH, "net.example.news", true},
+		{"alpha", routerShared.ACTION_CALL, "wamp.router.register", false},
+		{"beta", routerShared.ACTION_CALL, "net.example.public.echo", true},
+		{"beta", routerShared.ACTION_REGISTER, "net.example.public.echo", false},
+		{"beta", routerShared.ACTION_SUBSCRIBE, "net.example.news", true},
+		{"beta", routerShared.ACTION_SUBSCRIBE, "net.example.news.sport", false},
+		{"beta", routerShared.ACTION_PUBLISH, "net.example.chat.1", true},
+		{"beta", routerShared.ACTION_PUBLISH, "net.example.chat.general", false},
+		{"gamma", routerShared.ACTION_CALL, "net.example.public.echo", false},
+		{"unknown", routerShared.ACTION_CALL, "net.example.public.echo", false},
+		{"delta", routerShared.ACTION_REGISTER, "wamp.authenticate", true},
+		{"epsilon", routerShared.ACTION_REGISTER, "wamp.authenticate", false},
+		{"router", routerShared.ACTION_PUBLISH, "wamp.registration.new", false},
+	}
+	for _, testCase := range testCases {
+		e := authorizer.Authorize(testCase.peerID, testCase.action, testCase.uri)
+		if testCase.allowed != (e == nil) {
+			t.Fatalf("%s %s %s expected allowed=%t, but got %v", testCase.peerID, testCase.action, testCase.uri, testCase.allowed, e)
+		}
+	}
+
+	if authorizer.Role("gamma") != routerShared.ROLE_ANONYMOUS {
+		t.Fatalf("expected %s, but got %s", routerShared.ROLE_ANONYMOUS, authorizer.Role("gamma"))
+	}
+	if authorizer.Role("epsilon") != routerShared.ROLE_ANONYMOUS {
+		t.Fatalf("expected %s, but got %s", routerShared.ROLE_ANONYMOUS, authorizer.Role("epsilon"))
+	}
+	if authorizer.Role("delta") != routerShared.ROLE_LOCAL {
+		t.Fatalf("expected %s, but got %s", routerShared.ROLE_LOCAL, authorizer.Role("delta"))
+	}
+
+	authorizer.Forget("alpha")
+	e = authorizer.Authorize("alpha", routerShared.ACTION_REGISTER, "net.example.echo")
+	if e != routerShared.ErrorNotAuthorized {
+		t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+	}
+
+	// another peer which takes the same id does not inherit trust
+	authorizer.Assign("delta", "guest")
+	e = authorizer.Authorize("delta", routerShared.ACTION_REGISTER, "wamp.authenticate")
+	if e != routerShared.ErrorNotAuthorized {
+		t.Fatalf("expected %s, but got %v", routerShared.ErrorNotAuthorized, e)
+	}
+
+	t.Run("Case: Invalid rules are not loaded", func(t *testing.T) {
+		e := authorizer.Load(routerShared.Rules{
+			"guest": {{URI: "net.example.**", Actions: []string{"destroy"}}},
+		})
+		if e != routerShared.ErrorInvalidAction {
+			t.Fatalf("expected %s, but got %v", routerShared.ErrorInvalidAction, e)
+		}
+		e = authorizer.Authorize("beta", routerShared.ACTION_CALL, "net.example.public.echo")
+		if e != nil {
+			t.Fatalf("previous rules must be kept, but got %s", e)
+		}
+	})
+
+	t.Run("Case: Nil authorizer allows everything", func(t *testing.T) {
+		var authorizer *routerShared.Authorizer
+		e := authorizer.Authorize("beta", routerShared.ACTION_REGISTER, "net.example.echo")
+		if e != nil {
+			t.Fatalf("expected nil, but got %s", e)
+		}
+	})
+}
+
+func TestAuthorizerPatterns(t *testing.T) {
+	rules := routerShared.Rules{
+		"reader": {
+			{URI: "com.acme.*", Match: routerShared.MATCH_WILDCARD, Actions: []string{routerShared.ACTION_SUBSCRIBE, routerShared.ACTION_REGISTER}},
+			{URI: "net.public.echo", Match: routerShared.MATCH_EXACT, Actions: []string{routerShared.ACTION_REGISTER}},
+			{URI: "org.open", Match: routerShared.MATCH_PREFIX, Actions: []string{routerShared.ACTION_SUBSCRIBE}},
+			{URI: "edu.**", Match: routerShared.MATCH_WILDCARD, Actions: []string{routerShared.ACTION_SUBSCRIBE}},
+			{URI: `^io\.[a-z]+$`, Match: routerShared.MATCH_REGEX, Actions: []string{routerShared.ACTION_SUBSCRIBE}},
+		},
+	}
+	authorizer, e := routerShared.NewAuthorizer(rules, slog.Default())
+	if e != nil {
+		t.Fatalf("new authorizer error %s", e)
+	}
+	authorizer.Assign("alpha", "reader")
+
+	testCases := []struct {
+		action  string
+		uri     string
+		policy  string
+		allowed bool
+	}{
+		{routerShared.ACTION_SUBSCRIBE, "com.acme.news", routerShared.MATCH_EXACT, true},
+		{routerShared.ACTION_SUBSCRIBE, "com.acme.*", routerShared.MATCH_WILDCARD, true},
+		{routerShared.ACTION_SUBSCRIBE, "com.acme.private.secret", routerShared.MATCH_EXACT, false},
+		{routerShared.ACTION_SUBSCRIBE, "com.acme.**", routerShared.MATCH_WILDCARD, false},
+		{routerShared.ACTION_REGISTER, "com.acme.**", routerShared.MATCH_WILDCARD, false},
+		{routerShared.ACTION_SUBSCRIBE, "com.acme", routerShared.MATCH_PREFIX, false},
+		{routerShared.ACTION_SUBSCRIBE, "com.*.news", routerShared.MATCH_WILDCARD, false},
+		{routerShared.ACTION_REGISTER, "net.public.echo", routerShared.MATCH_EXACT, true},
+		{routerShared.ACTION_REGISTER, "net.public.echo", routerShared.MATCH_WILDCARD, true},
+		{routerShared.ACTION_REGISTER, "net.public.echo", routerShared.MATCH_PREFIX, false},
+		{routerShared.ACTION_SUBSCRIBE, "org.open.news", routerShared.MATCH_PREFIX, true},
+		{routerShared.ACTION_SUBSCRIBE, "org.open.**", routerShared.MATCH_WILDCARD, true},
+		{routerShared.ACTION_SUBSCRIBE, "org.open.*.daily", routerShared.MATCH_WILDCARD, true},
+		{routerShared.ACTION_SUBSCRIBE, "org", routerShared.MATCH_PREFIX, false},
+		{routerShared.ACTION_SUBSCRIBE, "org.*", routerShared.MATCH_WILDCARD, false},
+		{routerShared.ACTION_SUBSCRIBE, "edu", routerShared.MATCH_PREFIX, true},
+		{routerShared.ACTION_SUBSCRIBE, "edu.*.**", routerShared.MATCH_WILDCARD, true},
+		{routerShared.ACTION_SUBSCRIBE, "**", routerShared.MATCH_WILDCARD, false},
+		{routerShared.ACTION_SUBSCRIBE, `^io\.[a-z]+$`, routerShared.MATCH_REGEX, true},
+		{routerShared.ACTION_SUBSCRIBE, `^io\.`, routerShared.MATCH_REGEX, false},
+		{routerShared.ACTION_SUBSCRIBE, `^com\.acme\.news$`, routerShared.MATCH_REGEX, false},
+	}
+	for _, testCase := range testCases {
+		e := authorizer.AuthorizePattern("alpha", testCase.action, testCase.uri, testCase.policy)
+		if testCase.allowed != (e == nil) {
+			t.Fatalf("%s %s %s expected allowed=%t, but got %v", testCase.action, testCase.policy, testCase.uri, testCase.allowed, e)
+		}
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+
+	e := os.WriteFile(path, []byte(`{
+		"roles": {
+			"guest": [
+				{"URI": "net.example.public", "match": "prefix", "actions": ["call", "subscribe"]}
+			]
+		}
+	}`), 0600)
+	if e != nil {
+		t.Fatalf("write rules error %s", e)
+	}
+	rules, e := routerShared.ReadRules(path)
+	if e != nil {
+		t.Fatalf("read rules error %s", e)
+	}
+	authorizer, e := routerShared.NewAuthorizer(rules, slog.Default())
+	if e != nil {
+		t.Fatalf("new authorizer error %s", e)
+	}
+	authorizer.Assign("beta", "guest")
+	e = authorizer.Authorize("beta", routerShared.ACTION_SUBSCRIBE, "net.example.public.news")
+	if e != nil {
+		t.Fatalf("expected nil, but got %s", e)
+	}
+
+	e = os.WriteFile(path, []byte(`{"roles": {"guest": [{"URI": "net..example", "actions": ["call"]}]}}`), 0600)
+	if e != nil {
+		t.Fatalf("write rules error %s", e)
+	}
+	_, e = routerShared.ReadRules(path)
+	if e != routerShared.ErrorInvalidURI {
+		t.Fatalf("expected %s, but got %v", routerShared.ErrorInvalidURI, e)
+	}
+
+	_, e = routerShared.ReadRules(filepath.Join(t.TempDir(), "missing.json"))
+	if e == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestAuthorizerHook(t *testing.T) {
+	authorizer, e := routerShared.NewAuthorizer(nil, slog.Default())
+	if e != nil {
+		t.Fatalf("new authorizer error %s", e)
+	}
+
+	e = authorizer.Authorize("beta", routerShared.ACTION_CALL, "net.example.echo")
+	if e != nil {
+		t.Fatalf("nil rules must allow everything, but got %s", e)
+	}
+
+	calls := 0
+	available := true
+	authorizer.UseHook(
+		func(request routerShared.AuthorizationRequest) (bool, error) {
+			if !available {
+				return false, routerShared.ErrorHookUnavailable
+			}
+			calls++
+			return request.Role == "operator", nil
+		},
+		100*time.Millisecond,
+	)
+	authorizer.Assign("alpha", "operator")
+	authorizer.Assign("beta", "guest")
+
+	expect := func(peerID string, action string, uri string, expected error, expectedCalls int) {
+		t.Helper()
+		e := authorizer.Authorize(peerID, action, uri)
+		if e != expected {
+			t.Fatalf("%s %s %s expected %v, but got %v", peerID, action, uri, expected, e)
+		}
+		if calls != expectedCalls {
+			t.Fatalf("expected %d hook calls, but got %d", expectedCalls, calls)
+		}
+	}
+
+	expect("alpha", routerShared.ACTION_CALL, "net.example.echo", nil, 1)
+	// decision is cached
+	expect("alpha", routerShared.ACTION_CALL, "net.example.echo", nil, 1)
+	expect("beta", routerShared.ACTION_CALL, "net.example.echo", routerShared.ErrorNotAuthorized, 2)
+	// hook does not decide on its own registration
+	expect("beta", routerShared.ACTION_REGISTER, routerShared.AUTHORIZE_URI, nil, 2)
+	// nor on registrations which patterns match it
+	for pattern, policy := range map[string]string{
+		"wamp":    routerShared.MATCH_PREFIX,
+		"wamp.**": routerShared.MATCH_WILDCARD,
+		`^wamp\.`: routerShared.MATCH_REGEX,
+	} {
+		e := authorizer.AuthorizePattern("beta", routerShared.ACTION_REGISTER, pattern, policy)
+		if e != nil {
+			t.Fatalf("%s pattern %s expected nil, but got %s", policy, pattern, e)
+		}
+	}
+	e = authorizer.AuthorizePattern("beta", routerShared.ACTION_REGISTER, "wamp.auth", routerShared.MATCH_PREFIX)
+	if e != routerShared.ErrorNotAuthorized || calls != 3 {
+		t.Fatalf("expected %s after 3 hook calls, but got %v after %d", routerShared.ErrorNotAuthorized, e, calls)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	expect("alpha", routerShared.ACTION_CALL, "net.example.echo", nil, 4)
+
+	authorizer.Forget("alpha")
+	authorizer.Assign("alpha", "operator")
+	expect("alpha", routerShared.ACTION_CALL, "net.example.echo", nil, 5)
+
+	available = false
+	expect("beta", routerShared.ACTION_PUBLISH, "net.example.news", nil, 5)
+}
@@ -7,7 +7,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-type JWTClaims = jwt.RegisteredClaims
+type JWTClaims struct {
+	jwt.RegisteredClaims
+	// granted during authentication
+	Role string `json:"role,omitempty"`
+}
 
 func JWTSign(key *rsa.PrivateKey, claims *JWTClaims) (string, error) {
 	jwtoken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
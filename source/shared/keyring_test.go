@@ -29,10 +29,13 @@ func TestKeyRing(t *testing.T) {
 
 	now := time.Now()
 	expectedClaims := routerShared.JWTClaims{
-		Issuer:    wampShared.NewID(),
-		Subject:   wampShared.NewID(),
-		ExpiresAt: jwt.NewNumericDate(now.Add(7 * 24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(now),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    wampShared.NewID(),
+			Subject:   wampShared.NewID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(7 * 24 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Role: "operator",
 	}
 	ticket, e := keyRing.JWTSign(&expectedClaims)
 	if e != nil {
@@ -48,6 +51,10 @@ func TestKeyRing(t *testing.T) {
 		t.Fatalf("JWTParse expected %v, but got %v", expectedClaims.Issuer, claims.Issuer)
 	}
 
+	if claims.Role != expectedClaims.Role {
+		t.Fatalf("JWTParse expected %v, but got %v", expectedClaims.Role, claims.Role)
+	}
+
 	_, e = keyRing.JWTParse("invalid-ticket")
 	if e == nil {
 		t.Fatalf("Invalid behaviour")
@@ -0,0 +1,147 @@
+package routerShared
+
+import (
+	"time"
+
+	wamp "github.com/wamp3hub/wamp3go"
+)
+
+const (
+	MATCH_EXACT    = "exact"
+	MATCH_PREFIX   = "prefix"
+	MATCH_WILDCARD = "wildcard"
+	MATCH_REGEX    = "regex"
+	// for backward compatibility `*` and `**` are interpreted by default
+	DEFAULT_MATCH_POLICY = MATCH_WILDCARD
+)
+
+const (
+	// only one registration per procedure is allowed
+	INVOKE_SINGLE = "single"
+	// calls the earliest registration
+	INVOKE_FIRST = "first"
+	// calls the latest registration
+	INVOKE_LAST = "last"
+	// distributes calls in turn proportionally to registration weights
+	INVOKE_ROUNDROBIN = "roundrobin"
+	INVOKE_RANDOM     = "random"
+	// calls the executor having the fewest calls in flight
+	INVOKE_LEASTBUSY = "leastbusy"
+	// calls the executor using the smallest share of its concurrency limit
+	INVOKE_LEASTLOADED = "leastloaded"
+	// for backward compatibility calls are distributed in turn by default
+	DEFAULT_INVOCATION_POLICY = INVOKE_ROUNDROBIN
+)
+
+var invocationPolicies = NewSet([]string{
+	INVOKE_SINGLE,
+	INVOKE_FIRST,
+	INVOKE_LAST,
+	INVOKE_ROUNDROBIN,
+	INVOKE_RANDOM,
+	INVOKE_LEASTBUSY,
+	INVOKE_LEASTLOADED,
+})
+
+func ValidInvocationPolicy(v string) bool {
+	return invocationPolicies.Contains(v)
+}
+
+const (
+	// replies to caller immediately, executor is not notified
+	CANCEL_SKIP = "skip"
+	// notifies executor and replies to caller with its response
+	CANCEL_KILL = "kill"
+	// notifies executor and replies to caller immediately
+	CANCEL_KILLNOWAIT = "killnowait"
+	// for backward compatibility executor is notified without waiting by default
+	DEFAULT_CANCEL_MODE = CANCEL_KILLNOWAIT
+)
+
+var cancelModes = NewSet([]string{CANCEL_SKIP, CANCEL_KILL, CANCEL_KILLNOWAIT})
+
+func ValidCancelMode(v string) bool {
+	return cancelModes.Contains(v)
+}
+
+type ResourceOptions interface {
+	MatchPolicy() string
+}
+
+type SubscribeOptions struct {
+	wamp.SubscribeOptions
+	Match string `json:"match"`
+}
+
+func (options *SubscribeOptions) MatchPolicy() string {
+	if options == nil || len(options.Match) == 0 {
+		return DEFAULT_MATCH_POLICY
+	}
+	return options.Match
+}
+
+type RegisterOptions struct {
+	wamp.RegisterOptions
+	Match  string `json:"match"`
+	Invoke string `json:"invoke"`
+	// maximum number of calls executor handles at once, including calls of its other procedures,
+	// zero means unlimited
+	Concurrency int `json:"concurrency"`
+	// number of other registrations tried when executor times out or leaves during call,
+	// timeout of call is shared by all attempts
+	Failover int `json:"failover"`
+	// share of calls under round robin policy, zero means default weight
+	Weight int `json:"weight"`
+	// how calls are cancelled, declared by executor because cancel event can not carry it
+	Cancel string `json:"cancel"`
+	// seconds generator may live, zero means default lifetime
+	Lifetime int `json:"lifetime"`
+	// seconds generator may wait for next request of caller, zero means unlimited
+	IdleTimeout int `json:"idleTimeout"`
+}
+
+func (options *RegisterOptions) MatchPolicy() string {
+	if options == nil || len(options.Match) == 0 {
+		return DEFAULT_MATCH_POLICY
+	}
+	return options.Match
+}
+
+func (options *RegisterOptions) InvocationPolicy() string {
+	if options == nil || len(options.Invoke) == 0 {
+		return DEFAULT_INVOCATION_POLICY
+	}
+	return options.Invoke
+}
+
+func (options *RegisterOptions) DistributionWeight() int {
+	if options == nil || options.Weight <= 0 {
+		return 1
+	}
+	return options.Weight
+}
+
+func (options *RegisterOptions) CancelMode() string {
+	if options == nil || len(options.Cancel) == 0 {
+		return DEFAULT_CANCEL_MODE
+	}
+	return options.Cancel
+}
+
+func (options *RegisterOptions) GeneratorLifetime() time.Duration {
+	if options == nil || options.Lifetime <= 0 {
+		return time.Duration(wamp.DEFAULT_GENERATOR_LIFETIME) * time.Second
+	}
+	return time.Duration(options.Lifetime) * time.Second
+}
+
+func (options *RegisterOptions) GeneratorIdleTimeout() time.Duration {
+	if options == nil || options.IdleTimeout <= 0 {
+		return 0
+	}
+	return time.Duration(options.IdleTimeout) * time.Second
+}
+
+type Subscription = wamp.Resource[*SubscribeOptions]
+
+type Registration = wamp.Resource[*RegisterOptions]
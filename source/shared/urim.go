@@ -5,40 +5,124 @@ import (
 	"log/slog"
 	"regexp"
 	"strings"
+	"sync"
 
 	wamp "github.com/wamp3hub/wamp3go"
-	wampShared "github.com/wamp3hub/wamp3go/shared"
 )
 
+// record of batch write, nil `Data` deletes record
+type StorageRecord struct {
+	BucketName string
+	Key        string
+	Data       any
+}
+
+// decodes stored record into `data`
+type StorageDecoder func(data any) error
+
 type Storage interface {
 	Get(bucketName string, key string, data any) error
 	Set(bucketName string, key string, data any) error
-	Delete(bucketName string, key string)
+	// returns empty slice if bucket does not exist
+	Keys(bucketName string) ([]string, error)
+	// calls procedure for every record of bucket, stops on first error
+	ForEach(bucketName string, procedure func(key string, decode StorageDecoder) error) error
+	// writes all records in single transaction
+	Batch(records []StorageRecord) error
+	// does nothing if bucket or record does not exist
+	Delete(bucketName string, key string) error
 	Destroy() error
 }
 
+// implemented by storages which delay writes
+type StorageFlusher interface {
+	// writes delayed updates
+	Flush() error
+}
+
+var (
+	ErrorBucketNotFound     = errors.New("BucketNotFound")
+	ErrorRecordNotFound     = errors.New("RecordNotFound")
+	ErrorInvalidURI         = errors.New("InvalidURI")
+	ErrorInvalidMatchPolicy = errors.New("InvalidMatchPolicy")
+)
+
 var URI_RE, _ = regexp.Compile(`^(\*{1,2}|[_0-9a-z]+)(\.(\*{1,2}|[_0-9a-z]+))*$`)
 
 func ParseURI(v string) ([]string, error) {
 	if !URI_RE.MatchString(v) {
-		return nil, errors.New("InvalidURI")
+		return nil, ErrorInvalidURI
 	}
 
 	result := strings.Split(v, ".")
 	return result, nil
 }
 
-type URIM[T any] struct {
-	bucket  string
-	root    *URISegment[*wamp.Resource[T]]
-	storage Storage
-	logger  *slog.Logger
+// returns true if pattern of the match policy matches URI, the same way URIM does
+func PatternMatch(pattern string, policy string, uri string) bool {
+	if policy == MATCH_REGEX {
+		expression, e := regexp.Compile(pattern)
+		return e == nil && expression.MatchString(uri)
+	}
+
+	patternPath, e := ParseURI(pattern)
+	if e != nil {
+		return false
+	}
+	path, e := ParseURI(uri)
+	if e != nil {
+		return false
+	}
+
+	root := NewURISegment[struct{}](nil)
+	segment := root.GetSert(patternPath)
+	var segmentList URISegmentList[struct{}]
+	switch policy {
+	case MATCH_EXACT:
+		return root.Get(path) == segment
+	case MATCH_PREFIX:
+		segmentList = root.MatchPrefix(path)
+	case MATCH_WILDCARD:
+		segmentList = root.Match(path)
+	}
+	// intermediate segments match too, but only the last one is the pattern
+	for _, match := range segmentList {
+		if match == segment {
+			return true
+		}
+	}
+	return false
+}
+
+type regexPattern[T any] struct {
+	expression *regexp.Regexp
+	segment    *URISegment[T]
+}
+
+// Guarded by mutex, so safe for concurrent use
+type URIM[T ResourceOptions] struct {
+	mutex    sync.RWMutex
+	bucket   string
+	exact    *URISegment[*wamp.Resource[T]]
+	prefix   *URISegment[*wamp.Resource[T]]
+	wildcard *URISegment[*wamp.Resource[T]]
+	regex    map[string]*regexPattern[*wamp.Resource[T]]
+	storage  Storage
+	logger   *slog.Logger
 }
 
-func NewURIM[T any](storage Storage, logger *slog.Logger) *URIM[T] {
+func NewURIM[T ResourceOptions](
+	bucket string,
+	storage Storage,
+	logger *slog.Logger,
+) *URIM[T] {
 	return &URIM[T]{
-		wampShared.NewID(),
+		sync.RWMutex{},
+		bucket,
+		NewURISegment[*wamp.Resource[T]](nil),
 		NewURISegment[*wamp.Resource[T]](nil),
+		NewURISegment[*wamp.Resource[T]](nil),
+		make(map[string]*regexPattern[*wamp.Resource[T]]),
 		storage,
 		logger.With("name", "URIM"),
 	}
@@ -46,29 +130,129 @@ func NewURIM[T any](storage Storage, logger *slog.Logger) *URIM[T] {
 
 type ResourceList[T any] []*wamp.Resource[T]
 
+func (urim *URIM[T]) getRoot(policy string) (*URISegment[*wamp.Resource[T]], error) {
+	switch policy {
+	case MATCH_EXACT:
+		return urim.exact, nil
+	case MATCH_PREFIX:
+		return urim.prefix, nil
+	case MATCH_WILDCARD:
+		return urim.wildcard, nil
+	}
+	return nil, ErrorInvalidMatchPolicy
+}
+
+// returns segment which keeps resources of the pattern,
+// returns nil if segment does not exist and `create` is false
+func (urim *URIM[T]) getSegment(
+	uri string,
+	policy string,
+	create bool,
+) (*URISegment[*wamp.Resource[T]], error) {
+	if policy == MATCH_REGEX {
+		pattern, found := urim.regex[uri]
+		if found {
+			return pattern.segment, nil
+		}
+
+		expression, e := regexp.Compile(uri)
+		if e != nil {
+			return nil, ErrorInvalidURI
+		}
+
+		if !create {
+			return nil, nil
+		}
+
+		pattern = &regexPattern[*wamp.Resource[T]]{expression, NewURISegment[*wamp.Resource[T]](nil)}
+		urim.regex[uri] = pattern
+		return pattern.segment, nil
+	}
+
+	root, e := urim.getRoot(policy)
+	if e != nil {
+		return nil, e
+	}
+
+	path, e := ParseURI(uri)
+	if e != nil {
+		return nil, e
+	}
+
+	if create {
+		return root.GetSert(path), nil
+	}
+	return root.Get(path), nil
+}
+
 // returns empty slice if something went wrong
 func (urim *URIM[T]) Match(uri string) ResourceList[T] {
 	resourceList := ResourceList[T]{}
 	path, e := ParseURI(uri)
-	if e == nil {
-		for _, segment := range urim.root.Match(path) {
-			for _, resource := range segment.Data {
-				resourceList = append(resourceList, resource)
-			}
+	if e != nil {
+		return resourceList
+	}
+
+	urim.mutex.RLock()
+	defer urim.mutex.RUnlock()
+
+	segmentList := URISegmentList[*wamp.Resource[T]]{}
+	segment := urim.exact.Get(path)
+	if segment != nil {
+		segmentList = append(segmentList, segment)
+	}
+	segmentList = append(segmentList, urim.prefix.MatchPrefix(path)...)
+	segmentList = append(segmentList, urim.wildcard.Match(path)...)
+	for _, pattern := range urim.regex {
+		if pattern.expression.MatchString(uri) {
+			segmentList = append(segmentList, pattern.segment)
+		}
+	}
+
+	for _, segment := range segmentList {
+		for _, resource := range segment.Data {
+			resourceList = append(resourceList, resource)
 		}
 	}
 	return resourceList
 }
 
+// returns resources of the pattern, unlike `Match` it does not interpret pattern
+func (urim *URIM[T]) Lookup(uri string, policy string) (ResourceList[T], error) {
+	urim.mutex.RLock()
+	defer urim.mutex.RUnlock()
+
+	resourceList := ResourceList[T]{}
+	segment, e := urim.getSegment(uri, policy, false)
+	if segment != nil {
+		for _, resource := range segment.Data {
+			resourceList = append(resourceList, resource)
+		}
+	}
+	return resourceList, e
+}
+
 func (urim *URIM[T]) Count(uri string) int {
 	resourceList := urim.Match(uri)
 	return len(resourceList)
 }
 
-// returns empty slice if something went wrong
-func (urim *URIM[T]) GetByAuthor(ID string) ResourceList[T] {
+// returns empty slice if author has no resources
+func (urim *URIM[T]) getByAuthor(ID string) (ResourceList[T], error) {
 	resourceList := ResourceList[T]{}
 	e := urim.storage.Get(urim.bucket, ID, &resourceList)
+	if errors.Is(e, ErrorBucketNotFound) || errors.Is(e, ErrorRecordNotFound) {
+		return resourceList, nil
+	}
+	return resourceList, e
+}
+
+// returns empty slice if something went wrong
+func (urim *URIM[T]) GetByAuthor(ID string) ResourceList[T] {
+	urim.mutex.RLock()
+	defer urim.mutex.RUnlock()
+
+	resourceList, e := urim.getByAuthor(ID)
 	if e != nil {
 		urim.logger.Warn("during get from storage", "error", e)
 	}
@@ -77,61 +261,155 @@ func (urim *URIM[T]) GetByAuthor(ID string) ResourceList[T] {
 
 func (urim *URIM[T]) setByAuthor(ID string, newResourceList ResourceList[T]) error {
 	if len(newResourceList) == 0 {
-		urim.storage.Delete(urim.bucket, ID)
-		return nil
+		e := urim.storage.Delete(urim.bucket, ID)
+		return e
 	}
 
 	e := urim.storage.Set(urim.bucket, ID, newResourceList)
 	return e
 }
 
-func (urim *URIM[T]) DeleteByAuthor(ID string, resourceID string) ResourceList[T] {
+// returns removed resources, index stays untouched if storage update failed
+func (urim *URIM[T]) DeleteByAuthor(ID string, resourceID string) (ResourceList[T], error) {
 	shouldRemove := func(resource *wamp.Resource[T]) bool {
 		return len(resourceID) == 0 || resourceID == resource.ID
 	}
 
-	resourceList := urim.GetByAuthor(ID)
+	urim.mutex.Lock()
+	defer urim.mutex.Unlock()
+
 	removedResourceList := ResourceList[T]{}
+	resourceList, e := urim.getByAuthor(ID)
+	if e != nil {
+		return removedResourceList, e
+	}
+
 	newResourceList := ResourceList[T]{}
 	for _, resource := range resourceList {
 		if shouldRemove(resource) {
-			path, _ := ParseURI(resource.URI)
-			segment := urim.root.Get(path)
-			delete(segment.Data, resource.ID)
 			removedResourceList = append(removedResourceList, resource)
 		} else {
 			newResourceList = append(newResourceList, resource)
 		}
 	}
 
-	e := urim.setByAuthor(ID, newResourceList)
+	if len(removedResourceList) == 0 {
+		return removedResourceList, nil
+	}
+
+	e = urim.setByAuthor(ID, newResourceList)
 	if e != nil {
-		urim.logger.Error("during set to storage", "error", e)
+		return ResourceList[T]{}, e
+	}
+
+	for _, resource := range removedResourceList {
+		urim.remove(resource)
+	}
+	return removedResourceList, nil
+}
+
+// rebuilds index from storage, returns restored resources
+func (urim *URIM[T]) Load() (ResourceList[T], error) {
+	urim.mutex.Lock()
+	defer urim.mutex.Unlock()
+
+	resourceList := ResourceList[T]{}
+	e := urim.storage.ForEach(
+		urim.bucket,
+		func(authorID string, decode StorageDecoder) error {
+			authorResourceList := ResourceList[T]{}
+			e := decode(&authorResourceList)
+			if e != nil {
+				urim.logger.Warn("during restore", "error", e, "AuthorID", authorID)
+				return nil
+			}
+
+			for _, resource := range authorResourceList {
+				segment, e := urim.getSegment(resource.URI, resource.Options.MatchPolicy(), true)
+				if e != nil {
+					urim.logger.Warn("during restore", "error", e, "ID", resource.ID, "URI", resource.URI)
+					continue
+				}
+				segment.Data[resource.ID] = resource
+				resourceList = append(resourceList, resource)
+			}
+			return nil
+		},
+	)
+	return resourceList, e
+}
+
+// must be called with locked mutex
+func (urim *URIM[T]) remove(resource *wamp.Resource[T]) {
+	policy := resource.Options.MatchPolicy()
+	segment, _ := urim.getSegment(resource.URI, policy, false)
+	if segment == nil {
+		return
 	}
 
-	return removedResourceList
+	delete(segment.Data, resource.ID)
+	if policy == MATCH_REGEX && segment.Empty() {
+		delete(urim.regex, resource.URI)
+	}
 }
 
 func (urim *URIM[T]) Add(resource *wamp.Resource[T]) error {
-	path, e := ParseURI(resource.URI)
+	urim.mutex.Lock()
+	defer urim.mutex.Unlock()
+
+	policy := resource.Options.MatchPolicy()
+	// validates uri and policy before touching storage
+	_, e := urim.getSegment(resource.URI, policy, false)
+	if e != nil {
+		return e
+	}
+
+	resourceList, e := urim.getByAuthor(resource.AuthorID)
+	if e != nil {
+		return e
+	}
+
+	newResourceList := append(resourceList, resource)
+	e = urim.setByAuthor(resource.AuthorID, newResourceList)
 	if e == nil {
-		resourceList := urim.GetByAuthor(resource.AuthorID)
-		newResourceList := append(resourceList, resource)
-		e = urim.setByAuthor(resource.AuthorID, newResourceList)
-		if e == nil {
-			segment := urim.root.GetSert(path)
-			segment.Data[resource.ID] = resource
-		}
+		segment, _ := urim.getSegment(resource.URI, policy, true)
+		segment.Data[resource.ID] = resource
 	}
 	return e
 }
 
+// returns resources grouped by pattern
+func (urim *URIM[T]) Dump() []ResourceList[T] {
+	urim.mutex.RLock()
+	defer urim.mutex.RUnlock()
+
+	segmentList := URISegmentList[*wamp.Resource[T]]{}
+	for _, root := range []*URISegment[*wamp.Resource[T]]{urim.exact, urim.prefix, urim.wildcard} {
+		for _, path := range root.PathDump() {
+			segmentList = append(segmentList, root.Get(path))
+		}
+	}
+	for _, pattern := range urim.regex {
+		segmentList = append(segmentList, pattern.segment)
+	}
+
+	result := []ResourceList[T]{}
+	for _, segment := range segmentList {
+		resourceList := ResourceList[T]{}
+		for _, resource := range segment.Data {
+			resourceList = append(resourceList, resource)
+		}
+		result = append(result, resourceList)
+	}
+	return result
+}
+
 func (urim *URIM[T]) DumpURIList() []string {
 	result := []string{}
-	pathList := urim.root.PathDump()
-	for _, path := range pathList {
-		uri := strings.Join(path, ".")
-		result = append(result, uri)
+	for _, resourceList := range urim.Dump() {
+		if len(resourceList) > 0 {
+			result = append(result, resourceList[0].URI)
+		}
 	}
 	return result
 }
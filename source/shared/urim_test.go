@@ -1,17 +1,19 @@
 package routerShared_test
 
 import (
+	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
-	wamp "github.com/wamp3hub/wamp3go"
 	wampShared "github.com/wamp3hub/wamp3go/shared"
 	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 	routerStorages "github.com/wamp3hub/wamp3router/source/storages"
 )
 
 func TestURIM(t *testing.T) {
-	expectedRegistration := wamp.Registration{
+	expectedRegistration := routerShared.Registration{
 		ID:       wampShared.NewID(),
 		URI:      "net.example.echo",
 		AuthorID: wampShared.NewID(),
@@ -20,10 +22,9 @@ func TestURIM(t *testing.T) {
 
 	logger := slog.Default()
 
-	storagePath := "/tmp/" + wampShared.NewID() + ".db"
-	storage, _ := routerStorages.NewBoltDBStorage(storagePath)
+	storage := routerStorages.NewMemoryStorage()
 
-	urim := routerShared.NewURIM[*wamp.RegisterOptions](storage, logger)
+	urim := routerShared.NewURIM[*routerShared.RegisterOptions]("test", storage, logger)
 	e := urim.Add(&expectedRegistration)
 	if e != nil {
 		t.Fatalf("invalid behaviour %s", e)
@@ -39,7 +40,10 @@ func TestURIM(t *testing.T) {
 		t.Fatalf("invalid behaviour")
 	}
 
-	removedResourceList := urim.DeleteByAuthor(expectedRegistration.AuthorID, "")
+	removedResourceList, e := urim.DeleteByAuthor(expectedRegistration.AuthorID, "")
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
 	if len(removedResourceList) != 1 {
 		t.Fatalf("invalid behaviour")
 	}
@@ -49,3 +53,294 @@ func TestURIM(t *testing.T) {
 		t.Fatalf("invalid behaviour")
 	}
 }
+
+func TestURIMMultiLevelWildcard(t *testing.T) {
+	logger := slog.Default()
+
+	storage := routerStorages.NewMemoryStorage()
+
+	urim := routerShared.NewURIM[*routerShared.SubscribeOptions]("test", storage, logger)
+
+	authorID := wampShared.NewID()
+	for _, uri := range []string{"net.**", "**.echo", "net.**.echo"} {
+		subscription := routerShared.Subscription{
+			ID:       wampShared.NewID(),
+			URI:      uri,
+			AuthorID: authorID,
+			Options:  nil,
+		}
+		e := urim.Add(&subscription)
+		if e != nil {
+			t.Fatalf("invalid behaviour %s", e)
+		}
+	}
+
+	testCases := []struct {
+		uri      string
+		expected int
+	}{
+		{"net", 1},
+		{"net.example", 1},
+		{"net.echo", 3},
+		{"net.example.echo", 3},
+		{"com.example.echo", 1},
+		{"com.example", 0},
+	}
+	for _, testCase := range testCases {
+		count := urim.Count(testCase.uri)
+		if count != testCase.expected {
+			t.Fatalf("count %s expected %d, but got %d", testCase.uri, testCase.expected, count)
+		}
+	}
+
+	removedResourceList, e := urim.DeleteByAuthor(authorID, "")
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+	if len(removedResourceList) != 3 {
+		t.Fatalf("invalid behaviour")
+	}
+
+	if urim.Count("net.example.echo") != 0 {
+		t.Fatalf("invalid behaviour")
+	}
+}
+
+func TestURIMConsecutiveMultiLevelWildcards(t *testing.T) {
+	logger := slog.Default()
+
+	storage := routerStorages.NewMemoryStorage()
+
+	urim := routerShared.NewURIM[*routerShared.SubscribeOptions]("test", storage, logger)
+
+	subscription := routerShared.Subscription{
+		ID:       wampShared.NewID(),
+		URI:      "**.**.**.**.**.**.**.x",
+		AuthorID: wampShared.NewID(),
+		Options:  nil,
+	}
+	e := urim.Add(&subscription)
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+
+	path := strings.Repeat("a.", 30)
+	startedAt := time.Now()
+	if urim.Count(path+"x") != 1 || urim.Count(path+"y") != 0 {
+		t.Fatalf("invalid behaviour")
+	}
+	// every `**` trying every suffix takes seconds
+	elapsed := time.Since(startedAt)
+	if elapsed > 500*time.Millisecond {
+		t.Fatalf("match took %s", elapsed)
+	}
+}
+
+func TestURIMMatchPolicies(t *testing.T) {
+	logger := slog.Default()
+
+	storage := routerStorages.NewMemoryStorage()
+
+	urim := routerShared.NewURIM[*routerShared.SubscribeOptions]("test", storage, logger)
+
+	authorID := wampShared.NewID()
+	patterns := []struct {
+		uri    string
+		policy string
+	}{
+		{"com.acme.telemetry", routerShared.MATCH_EXACT},
+		{"com.acme.telemetry", routerShared.MATCH_PREFIX},
+		{"com.acme.*", routerShared.MATCH_WILDCARD},
+		{"com.acme.*", routerShared.MATCH_EXACT},
+		{`^com\.acme\.[a-z]+$`, routerShared.MATCH_REGEX},
+	}
+	for _, pattern := range patterns {
+		subscription := routerShared.Subscription{
+			ID:       wampShared.NewID(),
+			URI:      pattern.uri,
+			AuthorID: authorID,
+			Options:  &routerShared.SubscribeOptions{Match: pattern.policy},
+		}
+		e := urim.Add(&subscription)
+		if e != nil {
+			t.Fatalf("invalid behaviour %s", e)
+		}
+	}
+
+	testCases := []struct {
+		uri      string
+		expected int
+	}{
+		{"com.acme.telemetry", 4},
+		{"com.acme.telemetry.cpu", 1},
+		{"com.acme.telemetry.cpu.load", 1},
+		{"com.acme.other", 2},
+		{"com.acme.*", 2},
+		{"com.acme", 0},
+	}
+	for _, testCase := range testCases {
+		count := urim.Count(testCase.uri)
+		if count != testCase.expected {
+			t.Fatalf("count %s expected %d, but got %d", testCase.uri, testCase.expected, count)
+		}
+	}
+
+	t.Run("Case: Invalid pattern", func(t *testing.T) {
+		subscription := routerShared.Subscription{
+			ID:       wampShared.NewID(),
+			URI:      "com.acme.(",
+			AuthorID: authorID,
+			Options:  &routerShared.SubscribeOptions{Match: routerShared.MATCH_REGEX},
+		}
+		e := urim.Add(&subscription)
+		if e != routerShared.ErrorInvalidURI {
+			t.Fatalf("expected %s, but got %s", routerShared.ErrorInvalidURI, e)
+		}
+
+		subscription.URI = "com.acme"
+		subscription.Options.Match = "unknown"
+		e = urim.Add(&subscription)
+		if e != routerShared.ErrorInvalidMatchPolicy {
+			t.Fatalf("expected %s, but got %s", routerShared.ErrorInvalidMatchPolicy, e)
+		}
+	})
+
+	t.Run("Case: Dump", func(t *testing.T) {
+		groups := urim.Dump()
+		if len(groups) != len(patterns) {
+			t.Fatalf("dump expected %d groups, but got %d", len(patterns), len(groups))
+		}
+	})
+
+	removedResourceList, e := urim.DeleteByAuthor(authorID, "")
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+	if len(removedResourceList) != len(patterns) {
+		t.Fatalf("invalid behaviour")
+	}
+
+	if urim.Count("com.acme.telemetry") != 0 {
+		t.Fatalf("invalid behaviour")
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		policy   string
+		uri      string
+		expected bool
+	}{
+		{"wamp.authorize", routerShared.MATCH_EXACT, "wamp.authorize", true},
+		{"wamp.authorize.*", routerShared.MATCH_EXACT, "wamp.authorize", false},
+		{"wamp", routerShared.MATCH_PREFIX, "wamp.authorize", true},
+		{"wamp.authorize.x", routerShared.MATCH_PREFIX, "wamp.authorize", false},
+		{"wamp.*", routerShared.MATCH_WILDCARD, "wamp.authorize", true},
+		{"**", routerShared.MATCH_WILDCARD, "wamp.authorize", true},
+		{"wamp.authorize.*", routerShared.MATCH_WILDCARD, "wamp.authorize", false},
+		{`^wamp\.auth`, routerShared.MATCH_REGEX, "wamp.authorize", true},
+		{`^net\.`, routerShared.MATCH_REGEX, "wamp.authorize", false},
+		{"wamp..authorize", routerShared.MATCH_EXACT, "wamp.authorize", false},
+	}
+	for _, testCase := range testCases {
+		matched := routerShared.PatternMatch(testCase.pattern, testCase.policy, testCase.uri)
+		if matched != testCase.expected {
+			t.Fatalf("%s %s %s expected %t, but got %t", testCase.pattern, testCase.policy, testCase.uri, testCase.expected, matched)
+		}
+	}
+}
+
+func TestURIMLoad(t *testing.T) {
+	logger := slog.Default()
+
+	storage := routerStorages.NewMemoryStorage()
+
+	urim := routerShared.NewURIM[*routerShared.RegisterOptions]("registrations", storage, logger)
+	expectedRegistration := routerShared.Registration{
+		ID:       wampShared.NewID(),
+		URI:      "net.example.echo",
+		AuthorID: wampShared.NewID(),
+		Options:  &routerShared.RegisterOptions{Match: routerShared.MATCH_PREFIX},
+	}
+	e := urim.Add(&expectedRegistration)
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+
+	// emulates router restart
+	urim = routerShared.NewURIM[*routerShared.RegisterOptions]("registrations", storage, logger)
+	if urim.Count("net.example.echo") != 0 {
+		t.Fatalf("invalid behaviour")
+	}
+
+	restoredList, e := urim.Load()
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+	if len(restoredList) != 1 || restoredList[0].ID != expectedRegistration.ID {
+		t.Fatalf("load returns unexpected values %v", restoredList)
+	}
+
+	registrationList := urim.Match("net.example.echo.deep")
+	if len(registrationList) != 1 {
+		t.Fatalf("invalid behaviour")
+	}
+}
+
+type brokenStorage struct {
+	*routerStorages.MemoryStorage
+	e error
+}
+
+func (storage *brokenStorage) Set(bucketName string, key string, data any) error {
+	if storage.e != nil {
+		return storage.e
+	}
+	return storage.MemoryStorage.Set(bucketName, key, data)
+}
+
+func (storage *brokenStorage) Delete(bucketName string, key string) error {
+	if storage.e != nil {
+		return storage.e
+	}
+	return storage.MemoryStorage.Delete(bucketName, key)
+}
+
+func TestURIMStorageError(t *testing.T) {
+	logger := slog.Default()
+
+	storage := &brokenStorage{routerStorages.NewMemoryStorage(), nil}
+	urim := routerShared.NewURIM[*routerShared.RegisterOptions]("registrations", storage, logger)
+
+	registration := routerShared.Registration{
+		ID:       wampShared.NewID(),
+		URI:      "net.example.echo",
+		AuthorID: wampShared.NewID(),
+		Options:  &routerShared.RegisterOptions{},
+	}
+	e := urim.Add(&registration)
+	if e != nil {
+		t.Fatalf("invalid behaviour %s", e)
+	}
+
+	storage.e = errors.New("StorageUnavailable")
+
+	removedResourceList, e := urim.DeleteByAuthor(registration.AuthorID, "")
+	if e != storage.e || len(removedResourceList) != 0 {
+		t.Fatalf("delete expected %s, but got %v", storage.e, e)
+	}
+	if urim.Count("net.example.echo") != 1 {
+		t.Fatalf("index must stay untouched")
+	}
+
+	e = urim.Add(&routerShared.Registration{
+		ID:       wampShared.NewID(),
+		URI:      "net.example.reverse",
+		AuthorID: registration.AuthorID,
+		Options:  &routerShared.RegisterOptions{},
+	})
+	if e != storage.e || urim.Count("net.example.reverse") != 0 {
+		t.Fatalf("add expected %s, but got %v", storage.e, e)
+	}
+}
@@ -1,6 +1,9 @@
 package routerShared
 
-const WILD_CARD_SYMBOL = "*"
+const (
+	WILD_CARD_SYMBOL       = "*"
+	MULTI_WILD_CARD_SYMBOL = "**"
+)
 
 type Path []string
 
@@ -24,26 +27,76 @@ func NewURISegment[T any](parent *URISegment[T]) *URISegment[T] {
 
 type URISegmentList[T any] []*URISegment[T]
 
-func (segment *URISegment[T]) Match(path Path) URISegmentList[T] {
+// segment and number of path segments which are left to match
+type matchState[T any] struct {
+	segment *URISegment[T]
+	rest    int
+}
+
+// every state is explored once, otherwise consecutive `**` try the same suffixes again and again
+func (segment *URISegment[T]) match(path Path, explored *Set[matchState[T]]) URISegmentList[T] {
+	result := URISegmentList[T]{}
+
+	state := matchState[T]{segment, len(path)}
+	if explored.Contains(state) {
+		return result
+	}
+	explored.Add(state)
+
 	if len(path) == 0 {
-		return URISegmentList[T]{segment}
+		result = append(result, segment)
+	} else if !segment.Leaf() {
+		key := path[0]
+		child, found := segment.Children[key]
+		if found {
+			subResult := child.match(path[1:], explored)
+			result = append(result, subResult...)
+		}
+
+		child, found = segment.Children[WILD_CARD_SYMBOL]
+		if found {
+			subResult := child.match(path[1:], explored)
+			result = append(result, subResult...)
+		}
+	}
+
+	// `**` consumes zero or more segments
+	child, found := segment.Children[MULTI_WILD_CARD_SYMBOL]
+	if found {
+		for i := 0; i <= len(path); i++ {
+			subResult := child.match(path[i:], explored)
+			result = append(result, subResult...)
+		}
 	}
 
+	return result
+}
+
+// returns unique segments which patterns match the path
+func (segment *URISegment[T]) Match(path Path) URISegmentList[T] {
 	result := URISegmentList[T]{}
-	if segment.Leaf() {
+	visited := NewEmptySet[*URISegment[T]]()
+	for _, match := range segment.match(path, NewEmptySet[matchState[T]]()) {
+		if visited.Contains(match) {
+			continue
+		}
+		visited.Add(match)
+		result = append(result, match)
+	}
+	return result
+}
+
+// returns segments along the path, i.e. patterns which are prefixes of the path
+func (segment *URISegment[T]) MatchPrefix(path Path) URISegmentList[T] {
+	result := URISegmentList[T]{segment}
+	if len(path) == 0 {
 		return result
 	}
 
 	key := path[0]
 	child, found := segment.Children[key]
 	if found {
-		subResult := child.Match(path[1:])
-		result = append(result, subResult...)
-	}
-
-	child, found = segment.Children[WILD_CARD_SYMBOL]
-	if found {
-		subResult := child.Match(path[1:])
+		subResult := child.MatchPrefix(path[1:])
 		result = append(result, subResult...)
 	}
 
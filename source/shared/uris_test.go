@@ -56,6 +56,42 @@ func TestDump(t *testing.T) {
 		}
 	})
 
+	t.Run("Case: Match multi-level wildcard", func(t *testing.T) {
+		root := routerShared.NewURISegment[routerShared.Emptiness](nil)
+		insertResource(root, routerShared.Path{"net", "**"}, wampShared.NewID(), routerShared.Emptiness{})
+		insertResource(root, routerShared.Path{"**", "echo"}, wampShared.NewID(), routerShared.Emptiness{})
+		insertResource(root, routerShared.Path{"net", "**", "echo"}, wampShared.NewID(), routerShared.Emptiness{})
+		insertResource(root, routerShared.Path{"net", "**", "**"}, wampShared.NewID(), routerShared.Emptiness{})
+
+		testCases := []struct {
+			path     routerShared.Path
+			expected int
+		}{
+			{routerShared.Path{"net"}, 2},
+			{routerShared.Path{"net", "example"}, 2},
+			{routerShared.Path{"net", "echo"}, 4},
+			{routerShared.Path{"net", "example", "echo"}, 4},
+			{routerShared.Path{"net", "a", "b", "c", "echo"}, 4},
+			{routerShared.Path{"echo"}, 1},
+			{routerShared.Path{"com", "example", "echo"}, 1},
+			{routerShared.Path{"com", "example"}, 0},
+		}
+		for _, testCase := range testCases {
+			count := 0
+			for _, segment := range root.Match(testCase.path) {
+				if !segment.Empty() {
+					count++
+				}
+			}
+			if count != testCase.expected {
+				t.Fatalf(
+					"match %v expected %d, but got %d",
+					testCase.path, testCase.expected, count,
+				)
+			}
+		}
+	})
+
 	t.Run("Case: Dump", func(t *testing.T) {
 		pathDump := root.PathDump()
 		if len(expectedPathList) != len(pathDump) {
@@ -2,9 +2,10 @@ package routerStorages
 
 import (
 	"encoding/json"
-	"errors"
 
 	"github.com/boltdb/bolt"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
 type BoltDBStorage struct {
@@ -14,7 +15,6 @@ type BoltDBStorage struct {
 func NewBoltDBStorage(path string) (*BoltDBStorage, error) {
 	db, e := bolt.Open(path, 0600, nil)
 	if e != nil {
-		// TODO log error
 		return nil, e
 	}
 
@@ -24,7 +24,6 @@ func NewBoltDBStorage(path string) (*BoltDBStorage, error) {
 
 func (storage *BoltDBStorage) Destroy() error {
 	e := storage.super.Close()
-	// TODO log error
 	return e
 }
 
@@ -32,18 +31,17 @@ func (storage *BoltDBStorage) Get(bucketName string, key string, data any) error
 	getRecord := func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
-			return errors.New("BucketNotFound")
+			return ErrorBucketNotFound
 		}
 		raw := bucket.Get([]byte(key))
 		if len(raw) == 0 {
-			return errors.New("RecordNotFound")
+			return ErrorRecordNotFound
 		}
 		e := json.Unmarshal(raw, data)
 		return e
 	}
 
 	e := storage.super.View(getRecord)
-	// TODO log error
 	return e
 }
 
@@ -66,19 +64,109 @@ func (storage *BoltDBStorage) Set(bucketName string, key string, data any) error
 	}
 
 	e = storage.super.Update(putRecord)
-	// TODO log error
 	return e
 }
 
-func (storage *BoltDBStorage) Delete(bucketName string, key string) {
-	deleteRecord := func(tx *bolt.Tx) error {
+func (storage *BoltDBStorage) Keys(bucketName string) ([]string, error) {
+	result := []string{}
+	listKeys := func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		e := bucket.ForEach(
+			func(key []byte, value []byte) error {
+				result = append(result, string(key))
+				return nil
+			},
+		)
+		return e
+	}
+
+	e := storage.super.View(listKeys)
+	return result, e
+}
+
+func (storage *BoltDBStorage) ForEach(
+	bucketName string,
+	procedure func(key string, decode routerShared.StorageDecoder) error,
+) error {
+	iterate := func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
-		if bucket != nil {
-			bucket.Delete([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+		e := bucket.ForEach(
+			func(key []byte, value []byte) error {
+				decode := func(data any) error {
+					return json.Unmarshal(value, data)
+				}
+				return procedure(string(key), decode)
+			},
+		)
+		return e
+	}
+
+	e := storage.super.View(iterate)
+	return e
+}
+
+func (storage *BoltDBStorage) Batch(records []routerShared.StorageRecord) error {
+	rawRecords := make([][]byte, len(records))
+	for i, record := range records {
+		if record.Data == nil {
+			continue
+		}
+		raw, e := json.Marshal(record.Data)
+		if e != nil {
+			return e
+		}
+		rawRecords[i] = raw
+	}
+
+	writeRecords := func(tx *bolt.Tx) error {
+		for i, record := range records {
+			bucket := tx.Bucket([]byte(record.BucketName))
+			if record.Data == nil {
+				if bucket == nil {
+					continue
+				}
+				e := bucket.Delete([]byte(record.Key))
+				if e != nil {
+					return e
+				}
+				continue
+			}
+
+			if bucket == nil {
+				var e error
+				bucket, e = tx.CreateBucket([]byte(record.BucketName))
+				if e != nil {
+					return e
+				}
+			}
+			e := bucket.Put([]byte(record.Key), rawRecords[i])
+			if e != nil {
+				return e
+			}
 		}
 		return nil
 	}
 
-	storage.super.Update(deleteRecord)
-	// TODO log error
+	e := storage.super.Update(writeRecords)
+	return e
+}
+
+func (storage *BoltDBStorage) Delete(bucketName string, key string) error {
+	deleteRecord := func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		e := bucket.Delete([]byte(key))
+		return e
+	}
+
+	e := storage.super.Update(deleteRecord)
+	return e
 }
@@ -1,13 +1,14 @@
 package routerStorages
 
 import (
+	"path/filepath"
 	"testing"
 
-	"github.com/wamp3hub/wamp3go/shared"
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
 )
 
 func TestBoltDB(t *testing.T) {
-	path := "/tmp/" + wampShared.NewID() + ".db"
+	path := filepath.Join(t.TempDir(), "wamp3rd.db")
 	storage, e := NewBoltDBStorage(path)
 	if e != nil {
 		t.Fatal(e)
@@ -26,6 +27,14 @@ func TestBoltDB(t *testing.T) {
 	if e = storage.Get("test", "beta", v); e != nil && *v != true {
 		t.Fatal(e)
 	}
+	keys, e := storage.Keys("test")
+	if e != nil || len(keys) != 2 {
+		t.Fatalf("keys expected 2, but got %v (error=%s)", keys, e)
+	}
+	keys, e = storage.Keys("not_existing")
+	if e != nil || len(keys) != 0 {
+		t.Fatalf("keys expected 0, but got %v (error=%s)", keys, e)
+	}
 	storage.Delete("test", "alpha")
 	if e = storage.Get("test", "alpha", v); e == nil {
 		t.Fatal(e)
@@ -35,3 +44,17 @@ func TestBoltDB(t *testing.T) {
 		t.Fatal(e)
 	}
 }
+
+func TestBoltDBConformance(t *testing.T) {
+	testStorageConformance(
+		t,
+		func(t *testing.T) routerShared.Storage {
+			path := filepath.Join(t.TempDir(), "wamp3rd.db")
+			storage, e := NewBoltDBStorage(path)
+			if e != nil {
+				t.Fatal(e)
+			}
+			return storage
+		},
+	)
+}
@@ -0,0 +1,271 @@
+package routerStorages
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	wamp "github.com/wamp3hub/wamp3go"
+	wampShared "github.com/wamp3hub/wamp3go/shared"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+// runs checks which every `routerShared.Storage` implementation must pass,
+// `newStorage` must return new empty instance on every call
+func testStorageConformance(
+	t *testing.T,
+	newStorage func(t *testing.T) routerShared.Storage,
+) {
+	t.Run("Case: Missing bucket", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		e := storage.Get("not_existing", "alpha", new(bool))
+		if e != ErrorBucketNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorBucketNotFound, e)
+		}
+
+		keys, e := storage.Keys("not_existing")
+		if e != nil || len(keys) != 0 {
+			t.Fatalf("keys expected empty, but got %v (error=%v)", keys, e)
+		}
+	})
+
+	t.Run("Case: Missing record", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		if e := storage.Set("test", "alpha", true); e != nil {
+			t.Fatal(e)
+		}
+
+		e := storage.Get("test", "not_existing", new(bool))
+		if e != ErrorRecordNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorRecordNotFound, e)
+		}
+	})
+
+	t.Run("Case: Overwrite", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		if e := storage.Set("test", "alpha", "first"); e != nil {
+			t.Fatal(e)
+		}
+		if e := storage.Set("test", "alpha", "second"); e != nil {
+			t.Fatal(e)
+		}
+
+		v := ""
+		if e := storage.Get("test", "alpha", &v); e != nil || v != "second" {
+			t.Fatalf("get expected second, but got %s (error=%v)", v, e)
+		}
+
+		keys, e := storage.Keys("test")
+		if e != nil || len(keys) != 1 {
+			t.Fatalf("keys expected 1, but got %v (error=%v)", keys, e)
+		}
+	})
+
+	t.Run("Case: Delete", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		// must not fail on missing bucket and record
+		if e := storage.Delete("not_existing", "alpha"); e != nil {
+			t.Fatal(e)
+		}
+
+		if e := storage.Set("test", "alpha", true); e != nil {
+			t.Fatal(e)
+		}
+		if e := storage.Delete("test", "not_existing"); e != nil {
+			t.Fatal(e)
+		}
+		if e := storage.Delete("test", "alpha"); e != nil {
+			t.Fatal(e)
+		}
+
+		e := storage.Get("test", "alpha", new(bool))
+		if e != ErrorRecordNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorRecordNotFound, e)
+		}
+	})
+
+	t.Run("Case: ForEach", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		e := storage.ForEach(
+			"not_existing",
+			func(key string, decode routerShared.StorageDecoder) error {
+				t.Fatalf("unexpected record %s", key)
+				return nil
+			},
+		)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		expected := map[string]int{"alpha": 1, "beta": 2}
+		for key, v := range expected {
+			if e := storage.Set("test", key, v); e != nil {
+				t.Fatal(e)
+			}
+		}
+
+		result := map[string]int{}
+		e = storage.ForEach(
+			"test",
+			func(key string, decode routerShared.StorageDecoder) error {
+				v := 0
+				e := decode(&v)
+				result[key] = v
+				return e
+			},
+		)
+		if e != nil || !reflect.DeepEqual(expected, result) {
+			t.Fatalf("for each expected %v, but got %v (error=%v)", expected, result, e)
+		}
+
+		expectedError := errors.New("stop")
+		e = storage.ForEach(
+			"test",
+			func(key string, decode routerShared.StorageDecoder) error {
+				return expectedError
+			},
+		)
+		if e != expectedError {
+			t.Fatalf("expected %s, but got %v", expectedError, e)
+		}
+	})
+
+	t.Run("Case: Batch", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		if e := storage.Set("test", "alpha", 1); e != nil {
+			t.Fatal(e)
+		}
+
+		e := storage.Batch(
+			[]routerShared.StorageRecord{
+				{BucketName: "test", Key: "alpha", Data: nil},
+				{BucketName: "test", Key: "beta", Data: 2},
+				{BucketName: "other", Key: "gamma", Data: 3},
+				{BucketName: "not_existing", Key: "delta", Data: nil},
+			},
+		)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if e := storage.Get("test", "alpha", new(int)); e != ErrorRecordNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorRecordNotFound, e)
+		}
+		v := 0
+		if e := storage.Get("test", "beta", &v); e != nil || v != 2 {
+			t.Fatalf("get expected 2, but got %d (error=%v)", v, e)
+		}
+		if e := storage.Get("other", "gamma", &v); e != nil || v != 3 {
+			t.Fatalf("get expected 3, but got %d (error=%v)", v, e)
+		}
+
+		// nothing is written if any record can not be encoded
+		e = storage.Batch(
+			[]routerShared.StorageRecord{
+				{BucketName: "test", Key: "epsilon", Data: 5},
+				{BucketName: "test", Key: "zeta", Data: func() {}},
+			},
+		)
+		if e == nil {
+			t.Fatal("batch must fail")
+		}
+		if e := storage.Get("test", "epsilon", new(int)); e != ErrorRecordNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorRecordNotFound, e)
+		}
+	})
+
+	t.Run("Case: Concurrent writers", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		n := 32
+		wg := new(sync.WaitGroup)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := strconv.Itoa(i)
+				e := storage.Set("test", key, i)
+				if e != nil {
+					t.Errorf("set error %s", e)
+				}
+				e = storage.Set("test", "shared", i)
+				if e != nil {
+					t.Errorf("set error %s", e)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		keys, e := storage.Keys("test")
+		if e != nil || len(keys) != n+1 {
+			t.Fatalf("keys expected %d, but got %d (error=%v)", n+1, len(keys), e)
+		}
+		for i := 0; i < n; i++ {
+			v := -1
+			e := storage.Get("test", strconv.Itoa(i), &v)
+			if e != nil || v != i {
+				t.Fatalf("get expected %d, but got %d (error=%v)", i, v, e)
+			}
+		}
+	})
+
+	t.Run("Case: Resource fidelity", func(t *testing.T) {
+		storage := newStorage(t)
+		defer storage.Destroy()
+
+		expected := routerShared.ResourceList[*routerShared.RegisterOptions]{
+			&wamp.Resource[*routerShared.RegisterOptions]{
+				ID:       wampShared.NewID(),
+				URI:      "net.example.*",
+				AuthorID: wampShared.NewID(),
+				Options: &routerShared.RegisterOptions{
+					RegisterOptions: wamp.RegisterOptions{Route: []string{wampShared.NewID()}},
+					Match:           routerShared.MATCH_WILDCARD,
+				},
+			},
+		}
+		if e := storage.Set("test", "alpha", expected); e != nil {
+			t.Fatal(e)
+		}
+
+		v := routerShared.ResourceList[*routerShared.RegisterOptions]{}
+		if e := storage.Get("test", "alpha", &v); e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(expected, v) {
+			t.Fatalf("get expected %v, but got %v", expected[0], v[0])
+		}
+	})
+
+	t.Run("Case: Destroy", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if e := storage.Set("test", "alpha", true); e != nil {
+			t.Fatal(e)
+		}
+		if e := storage.Destroy(); e != nil {
+			t.Fatal(e)
+		}
+
+		e := storage.Get("test", "alpha", new(bool))
+		if e == nil {
+			t.Fatal("storage must not be usable after destroy")
+		}
+	})
+}
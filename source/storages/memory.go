@@ -0,0 +1,136 @@
+package routerStorages
+
+import (
+	"encoding/json"
+	"sync"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+// Keeps records in memory, useful for tests and ephemeral routers.
+// Records are kept in json to match semantics of persistent storages.
+type MemoryStorage struct {
+	mutex   sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{buckets: make(map[string]map[string][]byte)}
+}
+
+func (storage *MemoryStorage) Destroy() error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	clear(storage.buckets)
+	return nil
+}
+
+func (storage *MemoryStorage) Get(bucketName string, key string, data any) error {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	bucket, found := storage.buckets[bucketName]
+	if !found {
+		return ErrorBucketNotFound
+	}
+	raw := bucket[key]
+	if len(raw) == 0 {
+		return ErrorRecordNotFound
+	}
+	e := json.Unmarshal(raw, data)
+	return e
+}
+
+func (storage *MemoryStorage) Set(bucketName string, key string, data any) error {
+	raw, e := json.Marshal(data)
+	if e != nil {
+		return e
+	}
+
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	bucket, found := storage.buckets[bucketName]
+	if !found {
+		bucket = make(map[string][]byte)
+		storage.buckets[bucketName] = bucket
+	}
+	bucket[key] = raw
+	return nil
+}
+
+func (storage *MemoryStorage) Keys(bucketName string) ([]string, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	result := []string{}
+	for key := range storage.buckets[bucketName] {
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+func (storage *MemoryStorage) ForEach(
+	bucketName string,
+	procedure func(key string, decode routerShared.StorageDecoder) error,
+) error {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	for key, raw := range storage.buckets[bucketName] {
+		decode := func(data any) error {
+			return json.Unmarshal(raw, data)
+		}
+		e := procedure(key, decode)
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (storage *MemoryStorage) Batch(records []routerShared.StorageRecord) error {
+	rawRecords := make([][]byte, len(records))
+	for i, record := range records {
+		if record.Data == nil {
+			continue
+		}
+		raw, e := json.Marshal(record.Data)
+		if e != nil {
+			return e
+		}
+		rawRecords[i] = raw
+	}
+
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	for i, record := range records {
+		bucket, found := storage.buckets[record.BucketName]
+		if record.Data == nil {
+			if found {
+				delete(bucket, record.Key)
+			}
+			continue
+		}
+
+		if !found {
+			bucket = make(map[string][]byte)
+			storage.buckets[record.BucketName] = bucket
+		}
+		bucket[record.Key] = rawRecords[i]
+	}
+	return nil
+}
+
+func (storage *MemoryStorage) Delete(bucketName string, key string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	bucket, found := storage.buckets[bucketName]
+	if found {
+		delete(bucket, key)
+	}
+	return nil
+}
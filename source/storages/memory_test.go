@@ -0,0 +1,55 @@
+package routerStorages
+
+import (
+	"testing"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+func TestMemory(t *testing.T) {
+	storage := NewMemoryStorage()
+
+	type record struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		local string
+	}
+
+	t.Run("Case: JSON round trip", func(t *testing.T) {
+		expected := record{"alpha", []string{"a", "b"}, "not serialized"}
+		e := storage.Set("test", "alpha", &expected)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		// storage must keep a copy
+		expected.Tags[0] = "z"
+
+		v := new(record)
+		e = storage.Get("test", "alpha", v)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if v.Name != "alpha" || v.Tags[0] != "a" || len(v.local) != 0 {
+			t.Fatalf("get returns unexpected value %v", v)
+		}
+	})
+
+	storage.Delete("test", "alpha")
+	if e := storage.Get("test", "alpha", new(record)); e == nil {
+		t.Fatal("record must be deleted")
+	}
+
+	if e := storage.Destroy(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestMemoryConformance(t *testing.T) {
+	testStorageConformance(
+		t,
+		func(t *testing.T) routerShared.Storage {
+			return NewMemoryStorage()
+		},
+	)
+}
@@ -0,0 +1,59 @@
+package routerStorages
+
+import (
+	"errors"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+var (
+	ErrorBucketNotFound      = routerShared.ErrorBucketNotFound
+	ErrorRecordNotFound      = routerShared.ErrorRecordNotFound
+	ErrorUnknownStorageClass = errors.New("UnknownStorageClass")
+)
+
+// creates new instance of storage, `path` meaning depends on storage class
+type StorageFactory func(path string) (routerShared.Storage, error)
+
+var storageClasses = map[string]StorageFactory{
+	"BoltDB": func(path string) (routerShared.Storage, error) {
+		storage, e := NewBoltDBStorage(path)
+		if e != nil {
+			return nil, e
+		}
+		return storage, nil
+	},
+	"SQLite": func(path string) (routerShared.Storage, error) {
+		storage, e := NewSQLiteStorage(path)
+		if e != nil {
+			return nil, e
+		}
+		return storage, nil
+	},
+	"Memory": func(path string) (routerShared.Storage, error) {
+		return NewMemoryStorage(), nil
+	},
+}
+
+// makes storage class available for `NewStorage`
+func RegisterStorageClass(name string, factory StorageFactory) {
+	storageClasses[name] = factory
+}
+
+// returns names of available storage classes
+func StorageClasses() []string {
+	result := []string{}
+	for name := range storageClasses {
+		result = append(result, name)
+	}
+	return result
+}
+
+// creates new instance of storage by class name
+func NewStorage(class string, path string) (routerShared.Storage, error) {
+	factory, found := storageClasses[class]
+	if !found {
+		return nil, ErrorUnknownStorageClass
+	}
+	return factory(path)
+}
@@ -0,0 +1,27 @@
+package routerStorages
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStorage(t *testing.T) {
+	for _, class := range []string{"BoltDB", "SQLite", "Memory"} {
+		path := filepath.Join(t.TempDir(), "wamp3rd.db")
+		storage, e := NewStorage(class, path)
+		if e != nil {
+			t.Fatalf("new storage %s error %s", class, e)
+		}
+		if e = storage.Set("test", "alpha", true); e != nil {
+			t.Fatal(e)
+		}
+		if e = storage.Destroy(); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	_, e := NewStorage("Unknown", "")
+	if e != ErrorUnknownStorageClass {
+		t.Fatalf("expected %s, but got %s", ErrorUnknownStorageClass, e)
+	}
+}
@@ -0,0 +1,253 @@
+package routerStorages
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+// Keeps every bucket in separate table
+type SQLiteStorage struct {
+	super *sql.DB
+}
+
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	db, e := sql.Open("sqlite3", path)
+	if e == nil {
+		e = db.Ping()
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	// sqlite does not support concurrent writers
+	db.SetMaxOpenConns(1)
+
+	storage := &SQLiteStorage{super: db}
+	return storage, nil
+}
+
+func sqliteQuoteIdentifier(v string) string {
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
+func sqliteTableExists(tx *sql.Tx, tableName string) (bool, error) {
+	row := tx.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		tableName,
+	)
+	count := 0
+	e := row.Scan(&count)
+	return count > 0, e
+}
+
+// runs procedure in transaction, commits if procedure succeeded
+func (storage *SQLiteStorage) transaction(procedure func(*sql.Tx) error) error {
+	tx, e := storage.super.Begin()
+	if e != nil {
+		return e
+	}
+
+	e = procedure(tx)
+	if e != nil {
+		tx.Rollback()
+		return e
+	}
+
+	e = tx.Commit()
+	return e
+}
+
+func (storage *SQLiteStorage) Destroy() error {
+	e := storage.super.Close()
+	return e
+}
+
+func (storage *SQLiteStorage) Get(bucketName string, key string, data any) error {
+	getRecord := func(tx *sql.Tx) error {
+		exists, e := sqliteTableExists(tx, bucketName)
+		if e != nil {
+			return e
+		}
+		if !exists {
+			return ErrorBucketNotFound
+		}
+
+		raw := []byte{}
+		row := tx.QueryRow(
+			"SELECT value FROM "+sqliteQuoteIdentifier(bucketName)+" WHERE key = ?",
+			key,
+		)
+		e = row.Scan(&raw)
+		if errors.Is(e, sql.ErrNoRows) {
+			return ErrorRecordNotFound
+		}
+		if e != nil {
+			return e
+		}
+
+		e = json.Unmarshal(raw, data)
+		return e
+	}
+
+	e := storage.transaction(getRecord)
+	return e
+}
+
+func (storage *SQLiteStorage) Set(bucketName string, key string, data any) error {
+	raw, e := json.Marshal(data)
+	if e != nil {
+		return e
+	}
+
+	putRecord := func(tx *sql.Tx) error {
+		return sqlitePut(tx, bucketName, key, raw)
+	}
+
+	e = storage.transaction(putRecord)
+	return e
+}
+
+func sqlitePut(tx *sql.Tx, bucketName string, key string, raw []byte) error {
+	tableName := sqliteQuoteIdentifier(bucketName)
+	_, e := tx.Exec(
+		"CREATE TABLE IF NOT EXISTS " + tableName + " (key TEXT PRIMARY KEY, value BLOB NOT NULL)",
+	)
+	if e != nil {
+		return e
+	}
+
+	_, e = tx.Exec(
+		"INSERT INTO "+tableName+" (key, value) VALUES (?, ?) "+
+			"ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		key, raw,
+	)
+	return e
+}
+
+func sqliteDelete(tx *sql.Tx, bucketName string, key string) error {
+	exists, e := sqliteTableExists(tx, bucketName)
+	if e != nil || !exists {
+		return e
+	}
+
+	_, e = tx.Exec(
+		"DELETE FROM "+sqliteQuoteIdentifier(bucketName)+" WHERE key = ?",
+		key,
+	)
+	return e
+}
+
+func (storage *SQLiteStorage) Keys(bucketName string) ([]string, error) {
+	result := []string{}
+	listKeys := func(tx *sql.Tx) error {
+		exists, e := sqliteTableExists(tx, bucketName)
+		if e != nil || !exists {
+			return e
+		}
+
+		rows, e := tx.Query("SELECT key FROM " + sqliteQuoteIdentifier(bucketName))
+		if e != nil {
+			return e
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			key := ""
+			e = rows.Scan(&key)
+			if e != nil {
+				return e
+			}
+			result = append(result, key)
+		}
+		return rows.Err()
+	}
+
+	e := storage.transaction(listKeys)
+	return result, e
+}
+
+func (storage *SQLiteStorage) ForEach(
+	bucketName string,
+	procedure func(key string, decode routerShared.StorageDecoder) error,
+) error {
+	iterate := func(tx *sql.Tx) error {
+		exists, e := sqliteTableExists(tx, bucketName)
+		if e != nil || !exists {
+			return e
+		}
+
+		rows, e := tx.Query("SELECT key, value FROM " + sqliteQuoteIdentifier(bucketName))
+		if e != nil {
+			return e
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			key := ""
+			raw := []byte{}
+			e = rows.Scan(&key, &raw)
+			if e != nil {
+				return e
+			}
+
+			decode := func(data any) error {
+				return json.Unmarshal(raw, data)
+			}
+			e = procedure(key, decode)
+			if e != nil {
+				return e
+			}
+		}
+		return rows.Err()
+	}
+
+	e := storage.transaction(iterate)
+	return e
+}
+
+func (storage *SQLiteStorage) Batch(records []routerShared.StorageRecord) error {
+	rawRecords := make([][]byte, len(records))
+	for i, record := range records {
+		if record.Data == nil {
+			continue
+		}
+		raw, e := json.Marshal(record.Data)
+		if e != nil {
+			return e
+		}
+		rawRecords[i] = raw
+	}
+
+	writeRecords := func(tx *sql.Tx) error {
+		for i, record := range records {
+			var e error
+			if record.Data == nil {
+				e = sqliteDelete(tx, record.BucketName, record.Key)
+			} else {
+				e = sqlitePut(tx, record.BucketName, record.Key, rawRecords[i])
+			}
+			if e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	e := storage.transaction(writeRecords)
+	return e
+}
+
+func (storage *SQLiteStorage) Delete(bucketName string, key string) error {
+	deleteRecord := func(tx *sql.Tx) error {
+		return sqliteDelete(tx, bucketName, key)
+	}
+
+	e := storage.transaction(deleteRecord)
+	return e
+}
@@ -0,0 +1,22 @@
+package routerStorages
+
+import (
+	"path/filepath"
+	"testing"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+func TestSQLiteConformance(t *testing.T) {
+	testStorageConformance(
+		t,
+		func(t *testing.T) routerShared.Storage {
+			path := filepath.Join(t.TempDir(), "wamp3rd.sqlite")
+			storage, e := NewSQLiteStorage(path)
+			if e != nil {
+				t.Fatal(e)
+			}
+			return storage
+		},
+	)
+}
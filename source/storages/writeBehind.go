@@ -0,0 +1,256 @@
+package routerStorages
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+// Keeps updates in memory and writes them to underlying storage in batches.
+// Updates of the same record are coalesced, so only the last one is written.
+// Pending updates are flushed every `interval` or when `maxPending` records are waiting.
+type WriteBehindStorage struct {
+	super      routerShared.Storage
+	interval   time.Duration
+	maxPending int
+	mutex      sync.Mutex
+	flushMutex sync.Mutex
+	// nil record means deleted
+	pending map[string]map[string][]byte
+	// updates which are being written by flush
+	flushing map[string]map[string][]byte
+	count    int
+	stop     chan struct{}
+	done     chan struct{}
+	logger   *slog.Logger
+}
+
+func NewWriteBehindStorage(
+	super routerShared.Storage,
+	interval time.Duration,
+	maxPending int,
+	logger *slog.Logger,
+) *WriteBehindStorage {
+	storage := &WriteBehindStorage{
+		super:      super,
+		interval:   interval,
+		maxPending: maxPending,
+		pending:    make(map[string]map[string][]byte),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+		logger:     logger.With("name", "WriteBehindStorage"),
+	}
+	go storage.loop()
+	return storage
+}
+
+func (storage *WriteBehindStorage) loop() {
+	defer close(storage.done)
+
+	ticker := time.NewTicker(storage.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-storage.stop:
+			return
+		case <-ticker.C:
+			e := storage.Flush()
+			if e != nil {
+				storage.logger.Error("during flush", "error", e)
+			}
+		}
+	}
+}
+
+// must be called with locked mutex, returns true if flush is required
+func (storage *WriteBehindStorage) put(bucketName string, key string, raw []byte) bool {
+	bucket, found := storage.pending[bucketName]
+	if !found {
+		bucket = make(map[string][]byte)
+		storage.pending[bucketName] = bucket
+	}
+	_, found = bucket[key]
+	if !found {
+		storage.count++
+	}
+	bucket[key] = raw
+	return storage.maxPending > 0 && storage.count >= storage.maxPending
+}
+
+// writes pending updates to underlying storage in single batch,
+// keeps them pending if write failed
+func (storage *WriteBehindStorage) Flush() error {
+	storage.flushMutex.Lock()
+	defer storage.flushMutex.Unlock()
+
+	storage.mutex.Lock()
+	pending := storage.pending
+	storage.pending = make(map[string]map[string][]byte)
+	storage.flushing = pending
+	storage.count = 0
+	storage.mutex.Unlock()
+
+	defer func() {
+		storage.mutex.Lock()
+		storage.flushing = nil
+		storage.mutex.Unlock()
+	}()
+
+	records := []routerShared.StorageRecord{}
+	for bucketName, bucket := range pending {
+		for key, raw := range bucket {
+			record := routerShared.StorageRecord{BucketName: bucketName, Key: key}
+			if raw != nil {
+				record.Data = json.RawMessage(raw)
+			}
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	e := storage.super.Batch(records)
+	if e == nil {
+		storage.logger.Debug("flush", "recordsCount", len(records))
+		return nil
+	}
+
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	for bucketName, bucket := range pending {
+		for key, raw := range bucket {
+			// newer updates win
+			_, found := storage.pending[bucketName][key]
+			if !found {
+				storage.put(bucketName, key, raw)
+			}
+		}
+	}
+	return e
+}
+
+func (storage *WriteBehindStorage) flushIfRequired(required bool) error {
+	if !required {
+		return nil
+	}
+	return storage.Flush()
+}
+
+func (storage *WriteBehindStorage) Destroy() error {
+	close(storage.stop)
+	<-storage.done
+
+	e := storage.Flush()
+	if e != nil {
+		storage.logger.Error("during flush", "error", e)
+	}
+
+	e = storage.super.Destroy()
+	return e
+}
+
+// returns true if bucket is going to be created by pending updates
+func pendingBucketExists(bucket map[string][]byte) bool {
+	for _, raw := range bucket {
+		if raw != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (storage *WriteBehindStorage) Get(bucketName string, key string, data any) error {
+	storage.mutex.Lock()
+	raw, found := storage.pending[bucketName][key]
+	if !found {
+		raw, found = storage.flushing[bucketName][key]
+	}
+	bucketFound := pendingBucketExists(storage.pending[bucketName]) ||
+		pendingBucketExists(storage.flushing[bucketName])
+	storage.mutex.Unlock()
+
+	if found {
+		if raw == nil {
+			return ErrorRecordNotFound
+		}
+		e := json.Unmarshal(raw, data)
+		return e
+	}
+
+	e := storage.super.Get(bucketName, key, data)
+	if e == ErrorBucketNotFound && bucketFound {
+		return ErrorRecordNotFound
+	}
+	return e
+}
+
+func (storage *WriteBehindStorage) Set(bucketName string, key string, data any) error {
+	raw, e := json.Marshal(data)
+	if e != nil {
+		return e
+	}
+
+	storage.mutex.Lock()
+	required := storage.put(bucketName, key, raw)
+	storage.mutex.Unlock()
+
+	e = storage.flushIfRequired(required)
+	return e
+}
+
+func (storage *WriteBehindStorage) Keys(bucketName string) ([]string, error) {
+	e := storage.Flush()
+	if e != nil {
+		return []string{}, e
+	}
+	return storage.super.Keys(bucketName)
+}
+
+func (storage *WriteBehindStorage) ForEach(
+	bucketName string,
+	procedure func(key string, decode routerShared.StorageDecoder) error,
+) error {
+	e := storage.Flush()
+	if e != nil {
+		return e
+	}
+	return storage.super.ForEach(bucketName, procedure)
+}
+
+func (storage *WriteBehindStorage) Batch(records []routerShared.StorageRecord) error {
+	rawRecords := make([][]byte, len(records))
+	for i, record := range records {
+		if record.Data == nil {
+			continue
+		}
+		raw, e := json.Marshal(record.Data)
+		if e != nil {
+			return e
+		}
+		rawRecords[i] = raw
+	}
+
+	required := false
+	storage.mutex.Lock()
+	for i, record := range records {
+		required = storage.put(record.BucketName, record.Key, rawRecords[i]) || required
+	}
+	storage.mutex.Unlock()
+
+	e := storage.flushIfRequired(required)
+	return e
+}
+
+func (storage *WriteBehindStorage) Delete(bucketName string, key string) error {
+	storage.mutex.Lock()
+	required := storage.put(bucketName, key, nil)
+	storage.mutex.Unlock()
+
+	e := storage.flushIfRequired(required)
+	return e
+}
@@ -0,0 +1,123 @@
+package routerStorages
+
+import (
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	routerShared "github.com/wamp3hub/wamp3router/source/shared"
+)
+
+type countingStorage struct {
+	*MemoryStorage
+	mutex   sync.Mutex
+	batches [][]routerShared.StorageRecord
+}
+
+func (storage *countingStorage) Batch(records []routerShared.StorageRecord) error {
+	storage.mutex.Lock()
+	storage.batches = append(storage.batches, records)
+	storage.mutex.Unlock()
+	return storage.MemoryStorage.Batch(records)
+}
+
+func (storage *countingStorage) batchesCount() int {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return len(storage.batches)
+}
+
+func TestWriteBehind(t *testing.T) {
+	logger := slog.Default()
+
+	t.Run("Case: Coalesce", func(t *testing.T) {
+		super := &countingStorage{MemoryStorage: NewMemoryStorage()}
+		storage := NewWriteBehindStorage(super, time.Hour, 0, logger)
+		defer storage.Destroy()
+
+		for i := 0; i < 10; i++ {
+			if e := storage.Set("test", "alpha", i); e != nil {
+				t.Fatal(e)
+			}
+		}
+		if e := storage.Set("test", "beta", true); e != nil {
+			t.Fatal(e)
+		}
+		if e := storage.Delete("test", "beta"); e != nil {
+			t.Fatal(e)
+		}
+
+		// pending updates must be visible before flush
+		v := 0
+		if e := storage.Get("test", "alpha", &v); e != nil || v != 9 {
+			t.Fatalf("get expected 9, but got %d (error=%v)", v, e)
+		}
+		if e := super.Get("test", "alpha", &v); e != ErrorBucketNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorBucketNotFound, e)
+		}
+
+		if e := storage.Flush(); e != nil {
+			t.Fatal(e)
+		}
+		if super.batchesCount() != 1 || len(super.batches[0]) != 2 {
+			t.Fatalf("expected single batch of 2 records, but got %v", super.batches)
+		}
+		if e := super.Get("test", "alpha", &v); e != nil || v != 9 {
+			t.Fatalf("get expected 9, but got %d (error=%v)", v, e)
+		}
+		if e := super.Get("test", "beta", new(bool)); e != ErrorRecordNotFound {
+			t.Fatalf("expected %s, but got %v", ErrorRecordNotFound, e)
+		}
+	})
+
+	t.Run("Case: Max pending", func(t *testing.T) {
+		super := &countingStorage{MemoryStorage: NewMemoryStorage()}
+		storage := NewWriteBehindStorage(super, time.Hour, 2, logger)
+		defer storage.Destroy()
+
+		storage.Set("test", "alpha", true)
+		storage.Set("test", "alpha", true)
+		if super.batchesCount() != 0 {
+			t.Fatal("same record must be coalesced")
+		}
+		storage.Set("test", "beta", true)
+		if super.batchesCount() != 1 {
+			t.Fatal("storage must flush when max pending reached")
+		}
+	})
+
+	t.Run("Case: Interval", func(t *testing.T) {
+		super := &countingStorage{MemoryStorage: NewMemoryStorage()}
+		storage := NewWriteBehindStorage(super, 10*time.Millisecond, 0, logger)
+		defer storage.Destroy()
+
+		storage.Set("test", "alpha", true)
+		time.Sleep(100 * time.Millisecond)
+		if e := super.Get("test", "alpha", new(bool)); e != nil {
+			t.Fatalf("storage must flush on interval (error=%s)", e)
+		}
+	})
+
+	t.Run("Case: Destroy flushes", func(t *testing.T) {
+		super := &countingStorage{MemoryStorage: NewMemoryStorage()}
+		storage := NewWriteBehindStorage(super, time.Hour, 0, logger)
+
+		storage.Set("test", "alpha", true)
+		if e := storage.Destroy(); e != nil {
+			t.Fatal(e)
+		}
+		if super.batchesCount() != 1 {
+			t.Fatal("storage must flush on destroy")
+		}
+	})
+}
+
+func TestWriteBehindConformance(t *testing.T) {
+	testStorageConformance(
+		t,
+		func(t *testing.T) routerShared.Storage {
+			return NewWriteBehindStorage(NewMemoryStorage(), time.Millisecond, 0, slog.Default())
+		},
+	)
+}